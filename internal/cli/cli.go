@@ -116,6 +116,65 @@ func NewApp(version string, start time.Time) (app *cli.App) {
 				},
 			},
 		},
+		{
+			Name:  "s5",
+			Usage: "cipher and decipher s5 values using the engines configured in the defaults",
+			Subcommands: cli.Commands{
+				{
+					Name:   "cipher",
+					Usage:  "cipher a value read from stdin (or prompted for) and output an s5 payload",
+					Action: cmd.ExecWrapper(cmd.S5Cipher),
+				},
+				{
+					Name:      "decipher",
+					Usage:     "decipher an s5 payload passed as an argument or read from stdin (or prompted for)",
+					ArgsUsage: "[{{s5:...}}]",
+					Action:    cmd.ExecWrapper(cmd.S5Decipher),
+				},
+				{
+					Name:   "rotate",
+					Usage:  "re-cipher all the s5 values of the config file with a new key or engine",
+					Action: cmd.ExecWrapper(cmd.S5Rotate),
+					Flags:  append(s5Rotate, dryRun),
+				},
+			},
+		},
+		{
+			Name:  "state",
+			Usage: "manage the state of tfcw (variable expirations, managed variables..)",
+			Subcommands: cli.Commands{
+				{
+					Name:   "migrate",
+					Usage:  "move the state from a backend to another one",
+					Action: cmd.ExecWrapper(cmd.StateMigrate),
+					Flags:  append(stateMigrate, dryRun),
+				},
+			},
+		},
+		{
+			Name:  "variables",
+			Usage: "inspect the variables of the workspace",
+			Subcommands: cli.Commands{
+				{
+					Name:   "diff",
+					Usage:  "show the changes a rendering would apply onto the variables of the workspace",
+					Action: cmd.ExecWrapper(cmd.VariablesDiff),
+					Flags:  cli.FlagsByName{ignoreTTLs, jsonOutput},
+				},
+			},
+		},
+		{
+			Name:  "vault",
+			Usage: "manage the vault dynamic secrets",
+			Subcommands: cli.Commands{
+				{
+					Name:   "revoke",
+					Usage:  "revoke the leases of the dynamic secrets currently rendered on TFC",
+					Action: cmd.ExecWrapper(cmd.VaultRevoke),
+					Flags:  cli.FlagsByName{dryRun},
+				},
+			},
+		},
 		{
 			Name:    "workspace",
 			Aliases: []string{"ws"},
@@ -134,7 +193,7 @@ func NewApp(version string, start time.Time) (app *cli.App) {
 					Action: cmd.ExecWrapper(cmd.WorkspaceDeleteVariables),
 					Flags: cli.FlagsByName{&cli.BoolFlag{
 						Name:  "all, a",
-						Usage: "delete all variables",
+						Usage: "delete all variables (restricted to the ones created by tfcw when variables-ownership is 'managed')",
 					}},
 				},
 				{
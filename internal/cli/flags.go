@@ -49,7 +49,12 @@ var message = &cli.StringFlag{
 
 var ignoreTTLs = &cli.BoolFlag{
 	Name:  "ignore-ttls",
-	Usage: "render all variables, unconditionnaly of their current expirations or configured TTLs",
+	Usage: "render all variables, unconditionnaly of their current expirations, configured TTLs or fingerprints",
+}
+
+var jsonOutput = &cli.BoolFlag{
+	Name:  "json",
+	Usage: "output in JSON format",
 }
 
 var renderType = &cli.StringFlag{
@@ -57,3 +62,55 @@ var renderType = &cli.StringFlag{
 	Usage: "where to render to values - options are : tfc, local or disabled",
 	Value: "tfc",
 }
+
+var stateMigrate = cli.FlagsByName{
+	&cli.StringFlag{
+		Name:     "from",
+		Usage:    "`backend` to move the state from (workspace, file, vault or s3)",
+		Required: true,
+	},
+	&cli.StringFlag{
+		Name:  "to",
+		Usage: "`backend` to move the state to (default: the one configured in the state block)",
+	},
+}
+
+var s5Rotate = cli.FlagsByName{
+	&cli.StringFlag{
+		Name:  "engine",
+		Usage: "new s5 `engine` to cipher the values with (aes, age, aws, gcp, pgp or vault)",
+	},
+	&cli.StringFlag{
+		Name:    "aes-key",
+		EnvVars: []string{"TFCW_S5_NEW_AES_KEY"},
+		Usage:   "new AES `key` to cipher the values with",
+	},
+	&cli.StringFlag{
+		Name:  "age-identities-path",
+		Usage: "`path` of the new age identities file",
+	},
+	&cli.StringFlag{
+		Name:  "age-recipients-path",
+		Usage: "`path` of the new age recipients file",
+	},
+	&cli.StringFlag{
+		Name:  "aws-kms-key-arn",
+		Usage: "`arn` of the new AWS KMS key to cipher the values with",
+	},
+	&cli.StringFlag{
+		Name:  "gcp-kms-key-name",
+		Usage: "`name` of the new GCP KMS key to cipher the values with",
+	},
+	&cli.StringFlag{
+		Name:  "pgp-public-key-path",
+		Usage: "`path` of the new PGP public key",
+	},
+	&cli.StringFlag{
+		Name:  "pgp-private-key-path",
+		Usage: "`path` of the new PGP private key",
+	},
+	&cli.StringFlag{
+		Name:  "vault-transit-key",
+		Usage: "`name` of the new Vault transit key to cipher the values with",
+	},
+}
@@ -15,6 +15,13 @@ func Render(ctx *cli.Context) (int, error) {
 		return 1, err
 	}
 
+	// Fail fast, prior to interacting with TFC
+	if ctx.String("render-type") != "disabled" {
+		if err = c.ValidateVariables(cfg); err != nil {
+			return 1, err
+		}
+	}
+
 	switch ctx.String("render-type") {
 	case "tfc":
 		w, err := c.ConfigureWorkspace(cfg, ctx.Bool("dry-run"))
@@ -15,6 +15,13 @@ func RunCreate(ctx *cli.Context) (int, error) {
 		return 1, err
 	}
 
+	// Fail fast, prior to interacting with TFC
+	if ctx.String("render-type") != "disabled" {
+		if err = c.ValidateVariables(cfg); err != nil {
+			return 1, err
+		}
+	}
+
 	w, err := c.ConfigureWorkspace(cfg, false)
 	if err != nil {
 		return 1, err
@@ -3,10 +3,14 @@ package cmd
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
 )
 
 var wd, _ = os.Getwd()
@@ -78,3 +82,61 @@ func TestRunCreateWithDefaultValues(t *testing.T) {
 	assert.Equal(t, "tfcw config/hcl: <nil>: Configuration file not found; The configuration file  does not exist.", err.Error())
 	assert.Equal(t, 1, exitCode)
 }
+
+func TestRenderAndRunCreateWithMissingRequiredEnvVariable(t *testing.T) {
+	// Apart from the ping issued when creating the client, TFC should not get called
+	requests := []string{}
+	mutex := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v2/ping" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		mutex.Lock()
+		requests = append(requests, fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+		mutex.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	tmpDir, tmpFilePath, err := createTestConfigFile(fmt.Sprintf(`
+tfc {
+	address      = "%s"
+	token        = "_"
+	organization = "foo"
+
+	workspace {
+		name = "bar"
+	}
+}
+
+envvar "foo" {
+	env {
+		variable = "TFCW_TEST_MISSING_REQUIRED"
+		required = true
+	}
+}
+`, server.URL))
+	if err != nil {
+		t.Fatalf(fmt.Sprintf("error whilst creating temporary config file : %s", err.Error()))
+	}
+	defer os.Remove(tmpFilePath)
+	os.Unsetenv("TFCW_TEST_MISSING_REQUIRED")
+
+	for name, command := range map[string]func(*cli.Context) (int, error){
+		"render":     Render,
+		"run create": RunCreate,
+	} {
+		ctx, flags, globalFlags := NewTestContext()
+		flags.String("render-type", "tfc", "")
+		globalFlags.String("working-dir", tmpDir, "")
+		globalFlags.String("config-file", tmpFilePath, "")
+
+		exitCode, err := command(ctx)
+		assert.EqualError(t, err, "missing required environment variables: TFCW_TEST_MISSING_REQUIRED (variable 'foo')", name)
+		assert.Equal(t, 1, exitCode, name)
+	}
+
+	assert.Len(t, requests, 0)
+}
@@ -0,0 +1,418 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/manifoldco/promptui"
+	providerS5 "github.com/mvisonneau/tfcw/pkg/providers/s5"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/mvisonneau/tfcw/pkg/tfcw"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// stdin and stdout can be overridden in order to test the commands
+// reading and writing values
+var (
+	stdin  *os.File  = os.Stdin
+	stdout io.Writer = os.Stdout
+)
+
+// S5Cipher ciphers a value read from stdin (or prompted for) using the s5
+// engine configured in the defaults of the config file
+func S5Cipher(ctx *cli.Context) (int, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return 1, err
+	}
+
+	value, err := readInput("Value to cipher")
+	if err != nil {
+		return 1, err
+	}
+
+	output, err := tfcw.GetS5Client(cfg).Cipher(&schemas.S5{}, value)
+	if err != nil {
+		return 1, err
+	}
+
+	fmt.Fprintln(stdout, output)
+	return 0, nil
+}
+
+// S5Decipher deciphers an s5 payload passed as an argument or read from stdin
+// (or prompted for) using the s5 engine configured in the defaults of the config file
+func S5Decipher(ctx *cli.Context) (int, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return 1, err
+	}
+
+	value := ctx.Args().First()
+	if len(value) == 0 {
+		if value, err = readInput("Value to decipher"); err != nil {
+			return 1, err
+		}
+	}
+
+	output, err := tfcw.GetS5Client(cfg).GetValue(&schemas.S5{Value: &value})
+	if err != nil {
+		return 1, err
+	}
+
+	fmt.Fprintln(stdout, output)
+	return 0, nil
+}
+
+// readInput reads the value from stdin, or prompts for it if stdin is a terminal.
+// A trailing newline is removed from the value read from stdin
+func readInput(label string) (string, error) {
+	fi, err := stdin.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	if fi.Mode()&os.ModeCharDevice != 0 {
+		prompt := promptui.Prompt{
+			Label: label,
+			Mask:  '*',
+			Stdin: stdin,
+		}
+		return prompt.Run()
+	}
+
+	b, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r"), nil
+}
+
+// S5Rotate deciphers all the s5 values of the config file using the current engine
+// settings and ciphers them again using the ones provided through the flags. The config
+// file is rewritten in place, preserving its formatting and comments
+func S5Rotate(ctx *cli.Context) (int, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return 1, err
+	}
+
+	newS5, err := getS5RotationSettings(ctx)
+	if err != nil {
+		return 1, err
+	}
+
+	configFile := computeConfigFilePath(cfg.Runtime.WorkingDir, ctx.String("config-file"))
+	if filepath.Ext(configFile) != ".hcl" {
+		return 1, fmt.Errorf("only HCL config files can be rewritten, got '%s'", configFile)
+	}
+
+	rotated, err := rotateS5Values(cfg, configFile, newS5, ctx.Bool("dry-run"))
+	if err != nil {
+		return 1, err
+	}
+
+	if ctx.Bool("dry-run") {
+		log.Infof("[DRY-RUN] %d s5 value(s) would have been rotated in %s", rotated, configFile)
+		return 0, nil
+	}
+
+	log.Infof("%d s5 value(s) rotated in %s", rotated, configFile)
+	return 0, nil
+}
+
+// getS5RotationSettings returns the new engine settings provided through the flags
+func getS5RotationSettings(ctx *cli.Context) (*schemas.S5, error) {
+	s := &schemas.S5{}
+	updated := false
+
+	optionalString := func(name string) *string {
+		if !ctx.IsSet(name) {
+			return nil
+		}
+		updated = true
+		value := ctx.String(name)
+		return &value
+	}
+
+	if engine := optionalString("engine"); engine != nil {
+		engineType := schemas.S5CipherEngineType(*engine)
+		s.CipherEngineType = &engineType
+	}
+
+	if key := optionalString("aes-key"); key != nil {
+		s.CipherEngineAES = &schemas.S5CipherEngineAES{Key: key}
+	}
+
+	identitiesPath, recipientsPath := optionalString("age-identities-path"), optionalString("age-recipients-path")
+	if identitiesPath != nil || recipientsPath != nil {
+		s.CipherEngineAge = &schemas.S5CipherEngineAge{
+			IdentitiesPath: identitiesPath,
+			RecipientsPath: recipientsPath,
+		}
+	}
+
+	if kmsKeyArn := optionalString("aws-kms-key-arn"); kmsKeyArn != nil {
+		s.CipherEngineAWS = &schemas.S5CipherEngineAWS{KmsKeyArn: kmsKeyArn}
+	}
+
+	if kmsKeyName := optionalString("gcp-kms-key-name"); kmsKeyName != nil {
+		s.CipherEngineGCP = &schemas.S5CipherEngineGCP{KmsKeyName: kmsKeyName}
+	}
+
+	publicKeyPath, privateKeyPath := optionalString("pgp-public-key-path"), optionalString("pgp-private-key-path")
+	if publicKeyPath != nil || privateKeyPath != nil {
+		s.CipherEnginePGP = &schemas.S5CipherEnginePGP{
+			PublicKeyPath:  publicKeyPath,
+			PrivateKeyPath: privateKeyPath,
+		}
+	}
+
+	if transitKey := optionalString("vault-transit-key"); transitKey != nil {
+		s.CipherEngineVault = &schemas.S5CipherEngineVault{TransitKey: transitKey}
+	}
+
+	if !updated {
+		return nil, fmt.Errorf("you need to specify at least a new engine or key to rotate the s5 values to")
+	}
+
+	return s, nil
+}
+
+func rotateS5Values(cfg *schemas.Config, configFile string, newS5 *schemas.S5, dryRun bool) (rotated int, err error) {
+	content, err := ioutil.ReadFile(filepath.Clean(configFile))
+	if err != nil {
+		return
+	}
+
+	f, diags := hclwrite.ParseConfig(content, configFile, hcl.InitialPos)
+	if diags.HasErrors() {
+		return 0, fmt.Errorf("tfcw config/hcl: %s", diags.Error())
+	}
+
+	currentClient := tfcw.GetS5Client(cfg)
+	newClient := getS5RotationClient(cfg, newS5)
+
+	variables := map[string]*schemas.Variable{}
+	for _, v := range cfg.GetVariables() {
+		if v.S5 != nil && v.S5.Value != nil {
+			variables[fmt.Sprintf("%s/%s", v.Kind, v.Name)] = v
+		}
+	}
+
+	for _, block := range f.Body().Blocks() {
+		var kind schemas.VariableKind
+		switch block.Type() {
+		case "tfvar":
+			kind = schemas.VariableKindTerraform
+		case "envvar":
+			kind = schemas.VariableKindEnvironment
+		default:
+			continue
+		}
+
+		if len(block.Labels()) != 1 {
+			continue
+		}
+
+		v, found := variables[fmt.Sprintf("%s/%s", kind, block.Labels()[0])]
+		if !found {
+			continue
+		}
+
+		s5Block := block.Body().FirstMatchingBlock("s5", nil)
+		if s5Block == nil || s5Block.Body().GetAttribute("value") == nil {
+			continue
+		}
+
+		// Values coming from expressions (eg: env()) are defined outside of the config and cannot be rewritten
+		if !isLiteralString(s5Block.Body().GetAttribute("value")) {
+			log.Warnf("the s5 value of %s '%s' is not a literal string, it has to be rotated where it is defined", block.Type(), v.Name)
+			continue
+		}
+
+		var value, payload string
+		if value, err = currentClient.GetValue(v.S5); err != nil {
+			return 0, fmt.Errorf("unable to decipher the value of %s '%s': %s", block.Type(), v.Name, err)
+		}
+
+		if payload, err = newClient.Cipher(&schemas.S5{}, value); err != nil {
+			return 0, fmt.Errorf("unable to cipher the value of %s '%s': %s", block.Type(), v.Name, err)
+		}
+
+		// Variable specific engine settings would otherwise take precedence over the new ones
+		if removeS5EngineSettings(s5Block.Body()) {
+			log.Infof("removed the engine settings of %s '%s', the new defaults will be used instead", block.Type(), v.Name)
+		}
+
+		s5Block.Body().SetAttributeValue("value", cty.StringVal(payload))
+		log.Debugf("rotated the s5 value of %s '%s'", block.Type(), v.Name)
+		rotated++
+	}
+
+	updateS5Defaults(f.Body(), newS5)
+
+	if dryRun {
+		return
+	}
+
+	fi, err := os.Stat(configFile)
+	if err != nil {
+		return
+	}
+
+	err = ioutil.WriteFile(configFile, f.Bytes(), fi.Mode())
+	return
+}
+
+// getS5RotationClient returns a s5 client using the defaults of the config, overridden
+// by the new engine settings
+func getS5RotationClient(cfg *schemas.Config, newS5 *schemas.S5) *providerS5.Client {
+	c := tfcw.GetS5Client(cfg)
+
+	if newS5.CipherEngineType != nil {
+		c.CipherEngineType = newS5.CipherEngineType
+	}
+
+	if newS5.CipherEngineAES != nil {
+		c.CipherEngineAES = newS5.CipherEngineAES
+	}
+
+	if newS5.CipherEngineAge != nil {
+		age := &schemas.S5CipherEngineAge{}
+		if c.CipherEngineAge != nil {
+			*age = *c.CipherEngineAge
+		}
+		if newS5.CipherEngineAge.IdentitiesPath != nil {
+			age.IdentitiesPath = newS5.CipherEngineAge.IdentitiesPath
+		}
+		if newS5.CipherEngineAge.RecipientsPath != nil {
+			age.RecipientsPath = newS5.CipherEngineAge.RecipientsPath
+		}
+		c.CipherEngineAge = age
+	}
+
+	if newS5.CipherEngineAWS != nil {
+		c.CipherEngineAWS = newS5.CipherEngineAWS
+	}
+
+	if newS5.CipherEngineGCP != nil {
+		c.CipherEngineGCP = newS5.CipherEngineGCP
+	}
+
+	if newS5.CipherEnginePGP != nil {
+		pgp := &schemas.S5CipherEnginePGP{}
+		if c.CipherEnginePGP != nil {
+			*pgp = *c.CipherEnginePGP
+		}
+		if newS5.CipherEnginePGP.PublicKeyPath != nil {
+			pgp.PublicKeyPath = newS5.CipherEnginePGP.PublicKeyPath
+		}
+		if newS5.CipherEnginePGP.PrivateKeyPath != nil {
+			pgp.PrivateKeyPath = newS5.CipherEnginePGP.PrivateKeyPath
+		}
+		c.CipherEnginePGP = pgp
+	}
+
+	if newS5.CipherEngineVault != nil {
+		c.CipherEngineVault = newS5.CipherEngineVault
+	}
+
+	return c
+}
+
+var s5EngineBlockTypes = []string{"aes", "age", "aws", "gcp", "pgp", "vault"}
+
+func removeS5EngineSettings(body *hclwrite.Body) (removed bool) {
+	if body.GetAttribute("engine") != nil {
+		body.RemoveAttribute("engine")
+		removed = true
+	}
+
+	for _, blockType := range s5EngineBlockTypes {
+		if block := body.FirstMatchingBlock(blockType, nil); block != nil {
+			body.RemoveBlock(block)
+			removed = true
+		}
+	}
+	return
+}
+
+// updateS5Defaults writes the new engine settings in the defaults block. AES keys
+// being secrets, they are only updated if they were already written as literals in the config
+func updateS5Defaults(body *hclwrite.Body, newS5 *schemas.S5) {
+	s5Body := getOrCreateBlock(getOrCreateBlock(body, "defaults"), "s5")
+
+	if newS5.CipherEngineType != nil {
+		s5Body.SetAttributeValue("engine", cty.StringVal(string(*newS5.CipherEngineType)))
+	}
+
+	setAttribute := func(blockType, name string, value *string) {
+		if value != nil {
+			getOrCreateBlock(s5Body, blockType).SetAttributeValue(name, cty.StringVal(*value))
+		}
+	}
+
+	if newS5.CipherEngineAES != nil {
+		if aesBlock := s5Body.FirstMatchingBlock("aes", nil); aesBlock != nil && isLiteralString(aesBlock.Body().GetAttribute("key")) {
+			aesBlock.Body().SetAttributeValue("key", cty.StringVal(*newS5.CipherEngineAES.Key))
+		} else {
+			log.Warn("the new AES key has not been written in the config file, make sure to provide it using the S5_AES_KEY env variable")
+		}
+	}
+
+	if newS5.CipherEngineAge != nil {
+		setAttribute("age", "identities-path", newS5.CipherEngineAge.IdentitiesPath)
+		setAttribute("age", "recipients-path", newS5.CipherEngineAge.RecipientsPath)
+	}
+
+	if newS5.CipherEngineAWS != nil {
+		setAttribute("aws", "kms-key-arn", newS5.CipherEngineAWS.KmsKeyArn)
+	}
+
+	if newS5.CipherEngineGCP != nil {
+		setAttribute("gcp", "kms-key-name", newS5.CipherEngineGCP.KmsKeyName)
+	}
+
+	if newS5.CipherEnginePGP != nil {
+		setAttribute("pgp", "public-key-path", newS5.CipherEnginePGP.PublicKeyPath)
+		setAttribute("pgp", "private-key-path", newS5.CipherEnginePGP.PrivateKeyPath)
+	}
+
+	if newS5.CipherEngineVault != nil {
+		setAttribute("vault", "transit-key", newS5.CipherEngineVault.TransitKey)
+	}
+}
+
+// isLiteralString returns whether the attribute is defined and its expression is a plain string,
+// without any function call, reference or interpolation
+func isLiteralString(attribute *hclwrite.Attribute) bool {
+	if attribute == nil {
+		return false
+	}
+
+	expr, diags := hclsyntax.ParseExpression(attribute.Expr().BuildTokens(nil).Bytes(), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return false
+	}
+
+	value, diags := expr.Value(nil)
+	return !diags.HasErrors() && value.Type() == cty.String
+}
+
+func getOrCreateBlock(body *hclwrite.Body, blockType string) *hclwrite.Body {
+	if block := body.FirstMatchingBlock(blockType, nil); block != nil {
+		return block.Body()
+	}
+	return body.AppendNewBlock(blockType, nil).Body()
+}
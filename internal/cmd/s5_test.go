@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mvisonneau/s5/pkg/cipher"
+	"github.com/mvisonneau/tfcw/pkg/tfcw"
+	"github.com/stretchr/testify/assert"
+)
+
+const s5Config = `
+defaults {
+	s5 {
+		engine = "aes"
+		aes {
+			key = "cc6af4c2bf251c1cce0aebdbd39dc91d"
+		}
+	}
+}
+`
+
+func setTestStdin(t *testing.T, content string) func() {
+	f, err := ioutil.TempFile(os.TempDir(), "tfcw-test-stdin-")
+	assert.NoError(t, err)
+
+	_, err = f.Write([]byte(content))
+	assert.NoError(t, err)
+
+	_, err = f.Seek(0, 0)
+	assert.NoError(t, err)
+
+	stdin = f
+	return func() {
+		stdin = os.Stdin
+		f.Close()
+		os.Remove(f.Name())
+	}
+}
+
+func TestS5CipherAndDecipher(t *testing.T) {
+	tmpDir, tmpFilePath, err := createTestConfigFile(s5Config)
+	if err != nil {
+		t.Fatalf(fmt.Sprintf("error whilst creating temporary config file : %s", err.Error()))
+	}
+	defer os.Remove(tmpFilePath)
+
+	out := &bytes.Buffer{}
+	stdout = out
+	defer func() { stdout = os.Stdout }()
+
+	// Cipher
+	resetStdin := setTestStdin(t, "sensitive\n")
+	defer resetStdin()
+
+	ctx, _, globalFlags := NewTestContext()
+	globalFlags.String("working-dir", tmpDir, "")
+	globalFlags.String("config-file", tmpFilePath, "")
+
+	exitCode, err := S5Cipher(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+
+	payload := strings.TrimSpace(out.String())
+	assert.Regexp(t, `^{{s5:[A-Za-z0-9+/=]+}}$`, payload)
+
+	// Decipher from stdin
+	out.Reset()
+	resetStdin = setTestStdin(t, payload)
+	defer resetStdin()
+
+	exitCode, err = S5Decipher(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "sensitive\n", out.String())
+}
+
+func TestS5CipherWithDefaultValues(t *testing.T) {
+	ctx, _, _ := NewTestContext()
+	exitCode, err := S5Cipher(ctx)
+	assert.Equal(t, "tfcw config/hcl: <nil>: Configuration file not found; The configuration file  does not exist.", err.Error())
+	assert.Equal(t, 1, exitCode)
+}
+
+func TestS5DecipherInvalidPayload(t *testing.T) {
+	tmpDir, tmpFilePath, err := createTestConfigFile(s5Config)
+	if err != nil {
+		t.Fatalf(fmt.Sprintf("error whilst creating temporary config file : %s", err.Error()))
+	}
+	defer os.Remove(tmpFilePath)
+
+	resetStdin := setTestStdin(t, "foo")
+	defer resetStdin()
+
+	ctx, _, globalFlags := NewTestContext()
+	globalFlags.String("working-dir", tmpDir, "")
+	globalFlags.String("config-file", tmpFilePath, "")
+
+	exitCode, err := S5Decipher(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, exitCode)
+}
+
+func TestS5Rotate(t *testing.T) {
+	oldKey, otherKey, newKey := "cc6af4c2bf251c1cce0aebdbd39dc91d", "4177252ea44dea6b9d66815ab5dda08b", "3cf9d1b57c588f68bfd04b2e9644bd9e"
+
+	cipherValue := func(key, value string) string {
+		engine, err := cipher.NewAESClient(key)
+		assert.NoError(t, err)
+
+		ciphered, err := engine.Cipher(value)
+		assert.NoError(t, err)
+		return cipher.GenerateOutput(ciphered)
+	}
+
+	tmpDir, tmpFilePath, err := createTestConfigFile(fmt.Sprintf(`
+defaults {
+  s5 {
+    engine = "aes"
+    aes {
+      key = "%s"
+    }
+  }
+}
+
+// This comment must be preserved
+tfvar "foo" {
+  s5 {
+    value = "%s"
+  }
+}
+
+envvar "bar" {
+  s5 {
+    aes {
+      key = "%s"
+    }
+    value = "%s"
+  }
+}
+
+envvar "baz" {
+  env {
+    variable = "BAZ"
+  }
+}
+`, oldKey, cipherValue(oldKey, "foo"), otherKey, cipherValue(otherKey, "bar")))
+	if err != nil {
+		t.Fatalf(fmt.Sprintf("error whilst creating temporary config file : %s", err.Error()))
+	}
+	defer os.Remove(tmpFilePath)
+
+	ctx, flags, globalFlags := NewTestContext()
+	globalFlags.String("working-dir", tmpDir, "")
+	globalFlags.String("config-file", tmpFilePath, "")
+	flags.String("aes-key", "", "")
+	flags.Bool("dry-run", false, "")
+
+	// Without any new setting
+	exitCode, err := S5Rotate(ctx)
+	assert.EqualError(t, err, "you need to specify at least a new engine or key to rotate the s5 values to")
+	assert.Equal(t, 1, exitCode)
+
+	assert.NoError(t, flags.Set("aes-key", newKey))
+	exitCode, err = S5Rotate(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+
+	content, err := ioutil.ReadFile(tmpFilePath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "// This comment must be preserved")
+	assert.Contains(t, string(content), newKey)
+	assert.NotContains(t, string(content), oldKey)
+	assert.NotContains(t, string(content), otherKey)
+
+	// Values must now be deciphered using the new key
+	cfg, err := loadConfig(ctx)
+	assert.NoError(t, err)
+
+	c := tfcw.GetS5Client(cfg)
+	for _, v := range cfg.GetVariables() {
+		if v.S5 == nil {
+			continue
+		}
+
+		value, err := c.GetValue(v.S5)
+		assert.NoError(t, err)
+		assert.Equal(t, v.Name, value)
+	}
+}
+
+func TestS5RotateNonLiteralExpressions(t *testing.T) {
+	oldKey, newKey := "cc6af4c2bf251c1cce0aebdbd39dc91d", "3cf9d1b57c588f68bfd04b2e9644bd9e"
+
+	engine, err := cipher.NewAESClient(oldKey)
+	assert.NoError(t, err)
+
+	ciphered, err := engine.Cipher("foo")
+	assert.NoError(t, err)
+
+	os.Setenv("TFCW_TEST_S5_AES_KEY", oldKey)
+	os.Setenv("TFCW_TEST_S5_VALUE", cipher.GenerateOutput(ciphered))
+	defer os.Unsetenv("TFCW_TEST_S5_AES_KEY")
+	defer os.Unsetenv("TFCW_TEST_S5_VALUE")
+
+	config := `
+defaults {
+  s5 {
+    engine = "aes"
+    aes {
+      key = env("TFCW_TEST_S5_AES_KEY")
+    }
+  }
+}
+
+tfvar "foo" {
+  s5 {
+    value = env("TFCW_TEST_S5_VALUE")
+  }
+}
+`
+	tmpDir, tmpFilePath, err := createTestConfigFile(config)
+	if err != nil {
+		t.Fatalf(fmt.Sprintf("error whilst creating temporary config file : %s", err.Error()))
+	}
+	defer os.Remove(tmpFilePath)
+
+	ctx, flags, globalFlags := NewTestContext()
+	globalFlags.String("working-dir", tmpDir, "")
+	globalFlags.String("config-file", tmpFilePath, "")
+	flags.String("aes-key", "", "")
+	flags.Bool("dry-run", false, "")
+	assert.NoError(t, flags.Set("aes-key", newKey))
+
+	exitCode, err := S5Rotate(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+
+	// Expressions must be left untouched, the new key must not be written in plaintext
+	content, err := ioutil.ReadFile(tmpFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, config, string(content))
+	assert.NotContains(t, string(content), newKey)
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/urfave/cli/v2"
+)
+
+// StateMigrate moves the state of TFCW from a backend to another one
+func StateMigrate(ctx *cli.Context) (int, error) {
+	c, cfg, err := configure(ctx)
+	if err != nil {
+		return 1, err
+	}
+
+	w, err := c.GetWorkspace(cfg.Runtime.TFC.Organization, cfg.Runtime.TFC.Workspace)
+	if err != nil {
+		return 1, err
+	}
+
+	if err = c.MigrateState(cfg, w, schemas.StateBackend(ctx.String("from")), schemas.StateBackend(ctx.String("to")), ctx.Bool("dry-run")); err != nil {
+		return 1, err
+	}
+
+	return 0, nil
+}
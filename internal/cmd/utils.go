@@ -21,6 +21,21 @@ import (
 var start time.Time
 
 func configure(ctx *cli.Context) (c *tfcw.Client, cfg *schemas.Config, err error) {
+	if cfg, err = loadConfig(ctx); err != nil {
+		return
+	}
+
+	if err = computeRuntimeConfigurationForTFC(cfg, ctx); err != nil {
+		return
+	}
+
+	c, err = tfcw.NewClient(cfg)
+	return
+}
+
+// loadConfig configures the logger and parses the config file, without
+// requiring any access to TFC
+func loadConfig(ctx *cli.Context) (cfg *schemas.Config, err error) {
 	start = ctx.App.Metadata["startTime"].(time.Time)
 
 	if err = logger.Configure(logger.Config{
@@ -48,23 +63,20 @@ func configure(ctx *cli.Context) (c *tfcw.Client, cfg *schemas.Config, err error
 
 	err = hclsimple.DecodeFile(tfcwConfigFile, evalCtx, cfg)
 	if err != nil {
-		return c, cfg, fmt.Errorf("tfcw config/hcl: %s", err.Error())
-	}
-
-	if err = computeRuntimeConfigurationForTFC(cfg, ctx); err != nil {
-		return
+		return cfg, fmt.Errorf("tfcw config/hcl: %s", err.Error())
 	}
 
-	c, err = tfcw.NewClient(cfg)
 	return
 }
 
 func exit(exitCode int, err error) cli.ExitCoder {
-	defer log.WithFields(
-		log.Fields{
-			"execution-time": time.Since(start),
-		},
-	).Debug("exited..")
+	defer func() {
+		log.WithFields(
+			log.Fields{
+				"execution-time": time.Since(start),
+			},
+		).Debug("exited..")
+	}()
 
 	if err != nil {
 		log.Error(err.Error())
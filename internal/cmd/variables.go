@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+)
+
+// VariablesDiff displays the changes a rendering would apply onto the variables of the workspace
+func VariablesDiff(ctx *cli.Context) (int, error) {
+	c, cfg, err := configure(ctx)
+	if err != nil {
+		return 1, err
+	}
+
+	w, err := c.GetWorkspace(cfg.Runtime.TFC.Organization, cfg.Runtime.TFC.Workspace)
+	if err != nil {
+		return 1, err
+	}
+
+	diff, err := c.DiffVariables(cfg, w, ctx.Bool("ignore-ttls"))
+	if err != nil {
+		return 1, err
+	}
+
+	if ctx.Bool("json") {
+		output, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return 1, err
+		}
+		fmt.Println(string(output))
+		return 0, nil
+	}
+
+	fmt.Print(diff.String())
+	return 0, nil
+}
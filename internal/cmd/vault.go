@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/urfave/cli/v2"
+)
+
+// VaultRevoke revokes the leases of the Vault dynamic secrets currently rendered on TFC
+func VaultRevoke(ctx *cli.Context) (int, error) {
+	c, cfg, err := configure(ctx)
+	if err != nil {
+		return 1, err
+	}
+
+	w, err := c.GetWorkspace(cfg.Runtime.TFC.Organization, cfg.Runtime.TFC.Workspace)
+	if err != nil {
+		return 1, err
+	}
+
+	if err = c.RevokeVariableLeases(cfg, w, ctx.Bool("dry-run")); err != nil {
+		return 1, err
+	}
+
+	return 0, nil
+}
@@ -91,11 +91,11 @@ func WorkspaceDeleteVariables(ctx *cli.Context) (int, error) {
 	}
 
 	if ctx.Bool("all") {
-		if err = c.DeleteAllWorkspaceVariables(w); err != nil {
+		if err = c.DeleteAllWorkspaceVariables(cfg, w); err != nil {
 			return 1, err
 		}
 	} else {
-		if err = c.DeleteWorkspaceVariables(w, cfg.GetVariables()); err != nil {
+		if err = c.DeleteWorkspaceVariables(cfg, w); err != nil {
 			return 1, err
 		}
 	}
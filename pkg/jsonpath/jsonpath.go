@@ -0,0 +1,80 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Lookup returns the element found at path within data. The path is a dot separated list
+// of object keys or array indexes (eg: "foo.bar.0.baz"), an optional leading "$" is ignored
+func Lookup(data interface{}, path string) (interface{}, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if len(path) == 0 {
+		return data, nil
+	}
+
+	current := data
+	for _, element := range strings.Split(path, ".") {
+		switch c := current.(type) {
+		case map[string]interface{}:
+			value, found := c[element]
+			if !found {
+				return nil, fmt.Errorf("key '%s' of path '%s' not found", element, path)
+			}
+			current = value
+		case []interface{}:
+			i, err := strconv.Atoi(element)
+			if err != nil {
+				return nil, fmt.Errorf("'%s' of path '%s' is not a valid array index", element, path)
+			}
+
+			if i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("index '%d' of path '%s' is out of range", i, path)
+			}
+			current = c[i]
+		default:
+			return nil, fmt.Errorf("cannot lookup '%s' of path '%s' within a scalar value", element, path)
+		}
+	}
+
+	return current, nil
+}
+
+// Extract parses a JSON document and returns the string representation of the element found at path
+func Extract(b []byte, path string) (string, error) {
+	var data interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&data); err != nil {
+		return "", fmt.Errorf("invalid json: %s", err)
+	}
+
+	value, err := Lookup(data, path)
+	if err != nil {
+		return "", err
+	}
+
+	return String(value)
+}
+
+// String returns the string representation of a value, strings are returned
+// as is whilst other types are JSON encoded
+func String(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case nil:
+		return "", nil
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,66 @@
+package jsonpath
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDocument = `{
+  "foo": {
+    "bar": ["baz", {"qux": "quux"}],
+    "count": 12345678901234567890,
+    "enabled": true,
+    "nested": {"a": 1}
+  }
+}`
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"foo.bar.0", "baz"},
+		{"$.foo.bar.1.qux", "quux"},
+		{".foo.count", "12345678901234567890"},
+		{"foo.enabled", "true"},
+		{"foo.nested", `{"a":1}`},
+		{"foo.bar", `["baz",{"qux":"quux"}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			value, err := Extract([]byte(testDocument), test.path)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, value)
+		})
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	for _, path := range []string{"bar", "foo.bar.2", "foo.bar.baz", "foo.enabled.bar"} {
+		t.Run(path, func(t *testing.T) {
+			_, err := Extract([]byte(testDocument), path)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := Extract([]byte("{"), "foo")
+	assert.Error(t, err)
+}
+
+func TestLookupRoot(t *testing.T) {
+	value, err := Lookup("foo", "$")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", value)
+}
+
+func TestString(t *testing.T) {
+	s, err := String(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "", s)
+
+	s, err = String(map[string]interface{}{"foo": []interface{}{"bar"}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"foo":["bar"]}`, s)
+}
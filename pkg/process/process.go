@@ -0,0 +1,30 @@
+// Package process runs external commands on behalf of the providers
+package process
+
+import (
+	"context"
+	"os/exec"
+)
+
+// Run starts the command and waits for its completion. When the context gets done beforehand, the command
+// and the processes it may have spawned get killed. exec.CommandContext only kills the command itself, which
+// keeps Wait blocked for as long as its children hold the stdout and stderr pipes open
+func Run(ctx context.Context, cmd *exec.Cmd) error {
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+
+	return cmd.Wait()
+}
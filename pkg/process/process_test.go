@@ -0,0 +1,37 @@
+//go:build !windows
+// +build !windows
+
+package process
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("sh", "-c", "echo foo")
+	cmd.Stdout = &stdout
+	assert.NoError(t, Run(context.Background(), cmd))
+	assert.Equal(t, "foo\n", stdout.String())
+}
+
+func TestRunKillsChildren(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// The sleep is a child of the shell, it holds the stdout pipe open
+	var stdout bytes.Buffer
+	cmd := exec.Command("sh", "-c", "sleep 4; echo foo")
+	cmd.Stdout = &stdout
+
+	start := time.Now()
+	assert.Error(t, Run(ctx, cmd))
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+	assert.Empty(t, stdout.String())
+}
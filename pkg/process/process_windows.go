@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package process
+
+import "os/exec"
+
+// setProcessGroup is a no-op, process groups are not supported on windows
+func setProcessGroup(_ *exec.Cmd) {}
+
+// killProcessGroup kills the command
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mvisonneau/tfcw/pkg/jsonpath"
+	"github.com/mvisonneau/tfcw/pkg/process"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+// DefaultTimeout is the duration after which a command execution gets killed
+const DefaultTimeout = 30 * time.Second
+
+// Client is here to support provider related functions
+type Client struct {
+	WorkingDir string
+}
+
+// GetValue executes the command and returns its output
+func (c *Client) GetValue(cmd *schemas.Command) (string, error) {
+	if cmd == nil || len(cmd.Command) == 0 {
+		return "", fmt.Errorf("no command defined")
+	}
+
+	timeout := DefaultTimeout
+	if cmd.Timeout != nil {
+		var err error
+		if timeout, err = time.ParseDuration(*cmd.Timeout); err != nil {
+			return "", fmt.Errorf("invalid timeout '%s': %s", *cmd.Timeout, err)
+		}
+	}
+
+	var args []string
+	if cmd.Args != nil {
+		args = *cmd.Args
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	e := exec.Command(c.getCommandPath(cmd.Command), args...) // #nosec G204
+	e.Dir = c.getWorkingDir(cmd.WorkingDir)
+	e.Stdout = &stdout
+	e.Stderr = &stderr
+
+	if cmd.Env != nil {
+		e.Env = os.Environ()
+		for k, v := range *cmd.Env {
+			e.Env = append(e.Env, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+
+	if err := process.Run(ctx, e); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("command '%s' timed out after %s", cmd.Command, timeout.String())
+		}
+		return "", fmt.Errorf("command '%s' execution error: %s (stderr: %s)", cmd.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if cmd.JSONPath != nil {
+		value, err := jsonpath.Extract(stdout.Bytes(), *cmd.JSONPath)
+		if err != nil {
+			return "", fmt.Errorf("unable to extract '%s' from the output of command '%s': %s", *cmd.JSONPath, cmd.Command, err)
+		}
+		return value, nil
+	}
+
+	if cmd.Trim == nil || *cmd.Trim {
+		return strings.TrimSpace(stdout.String()), nil
+	}
+
+	return stdout.String(), nil
+}
+
+// GetVariableValues returns the value of a variable from the output of its command
+func (c *Client) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	value, err := c.GetValue(v.Command)
+	if err != nil {
+		return nil, fmt.Errorf("error getting value from command for variable '%s' : %s", v.Name, err)
+	}
+
+	return schemas.VariablesWithValues{
+		&schemas.VariableWithValue{
+			Variable: *v,
+			Value:    value,
+		},
+	}, nil
+}
+
+// getCommandPath resolves relative paths from the working directory, commands
+// without any path separator are looked up in the PATH
+func (c *Client) getCommandPath(command string) string {
+	if strings.ContainsRune(command, filepath.Separator) && !filepath.IsAbs(command) {
+		return filepath.Join(c.WorkingDir, command)
+	}
+	return command
+}
+
+func (c *Client) getWorkingDir(workingDir *string) string {
+	if workingDir == nil {
+		return c.WorkingDir
+	}
+
+	if filepath.IsAbs(*workingDir) {
+		return *workingDir
+	}
+
+	return filepath.Join(c.WorkingDir, *workingDir)
+}
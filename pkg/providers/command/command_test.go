@@ -0,0 +1,126 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetValue(t *testing.T) {
+	c := &Client{}
+
+	// Trimmed by default
+	value, err := c.GetValue(&schemas.Command{
+		Command: "echo",
+		Args:    &[]string{"foo"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", value)
+
+	// Untrimmed
+	value, err = c.GetValue(&schemas.Command{
+		Command: "echo",
+		Args:    &[]string{"foo"},
+		Trim:    pointy.Bool(false),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo\n", value)
+
+	// Environment variables
+	value, err = c.GetValue(&schemas.Command{
+		Command: "sh",
+		Args:    &[]string{"-c", "echo ${FOO}"},
+		Env:     &map[string]string{"FOO": "bar"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", value)
+
+	// JSON path
+	value, err = c.GetValue(&schemas.Command{
+		Command:  "echo",
+		Args:     &[]string{`{"Credentials":{"AccessKeyId":"foo"}}`},
+		JSONPath: pointy.String("Credentials.AccessKeyId"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", value)
+}
+
+func TestGetValueWorkingDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tfcw-test-command")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "foo"), []byte("bar"), 0o600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "script.sh"), []byte("#!/bin/sh\necho baz\n"), 0o700)) // #nosec G306
+
+	c := &Client{WorkingDir: dir}
+
+	value, err := c.GetValue(&schemas.Command{
+		Command:    "cat",
+		Args:       &[]string{"foo"},
+		WorkingDir: pointy.String("sub"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", value)
+
+	value, err = c.GetValue(&schemas.Command{
+		Command: "./script.sh",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "baz", value)
+}
+
+func TestGetValueErrors(t *testing.T) {
+	c := &Client{}
+
+	_, err := c.GetValue(&schemas.Command{})
+	assert.EqualError(t, err, "no command defined")
+
+	_, err = c.GetValue(&schemas.Command{Command: "true", Timeout: pointy.String("foo")})
+	assert.Error(t, err)
+
+	_, err = c.GetValue(&schemas.Command{Command: "sh", Args: &[]string{"-c", "echo oops >&2; exit 1"}})
+	assert.EqualError(t, err, "command 'sh' execution error: exit status 1 (stderr: oops)")
+
+	_, err = c.GetValue(&schemas.Command{Command: "sleep", Args: &[]string{"5"}, Timeout: pointy.String("100ms")})
+	assert.EqualError(t, err, "command 'sleep' timed out after 100ms")
+
+	_, err = c.GetValue(&schemas.Command{Command: "echo", Args: &[]string{"foo"}, JSONPath: pointy.String("foo")})
+	assert.Error(t, err)
+}
+
+func TestGetValueTimeoutWithChildren(t *testing.T) {
+	// The sleep is a child of the script, it holds the stdout and stderr pipes open
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "script.sh"), []byte("#!/bin/sh\nsleep 4\necho foo\n"), 0o700)) // #nosec G306
+
+	c := &Client{WorkingDir: dir}
+	start := time.Now()
+	_, err := c.GetValue(&schemas.Command{Command: "./script.sh", Timeout: pointy.String("200ms")})
+	assert.EqualError(t, err, "command './script.sh' timed out after 200ms")
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+}
+
+func TestGetVariableValues(t *testing.T) {
+	c := &Client{}
+	values, err := c.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		Command: &schemas.Command{
+			Command: "echo",
+			Args:    &[]string{"bar"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, values, 1)
+	assert.Equal(t, "bar", values[0].Value)
+
+	_, err = c.GetVariableValues(&schemas.Variable{Name: "foo", Command: &schemas.Command{}})
+	assert.EqualError(t, err, "error getting value from command for variable 'foo' : no command defined")
+}
@@ -1,16 +1,74 @@
 package env
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/mvisonneau/tfcw/pkg/schemas"
+	log "github.com/sirupsen/logrus"
 )
 
 // Client is a basic struct in order to support provider
 // related functions
 type Client struct{}
 
-// GetValue returns a value from an environment variable
+// GetValue returns a value from an environment variable, the default value is used
+// when it is not defined (or empty, unless explicitly allowed)
 func (c *Client) GetValue(e *schemas.Env) string {
-	return os.Getenv(e.Variable)
+	value, _ := lookupValue(e)
+	return value
+}
+
+// lookupValue returns the value of the environment variable and whether it has been found
+func lookupValue(e *schemas.Env) (value string, found bool) {
+	value, found = os.LookupEnv(e.Variable)
+	if found && len(value) == 0 && (e.AllowEmpty == nil || !*e.AllowEmpty) {
+		found = false
+	}
+
+	if !found && e.Default != nil {
+		return *e.Default, true
+	}
+
+	return
+}
+
+// GetVariableValues returns the value of a variable from its environment variable
+func (c *Client) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	value, found := lookupValue(v.Env)
+	if !found {
+		if v.Env.Required != nil && *v.Env.Required {
+			return nil, fmt.Errorf("environment variable '%s' is not defined (or empty) for variable '%s'", v.Env.Variable, v.Name)
+		}
+		log.Warnf("environment variable '%s' is not defined (or empty), using an empty value for variable '%s'", v.Env.Variable, v.Name)
+	}
+
+	return schemas.VariablesWithValues{
+		&schemas.VariableWithValue{
+			Variable: *v,
+			Value:    value,
+		},
+	}, nil
+}
+
+// ValidateVariables ensures that the environment variables of all the required
+// variables are defined, listing all the missing ones at once
+func (c *Client) ValidateVariables(vars schemas.Variables) error {
+	missing := []string{}
+	for _, v := range vars {
+		if v.Env == nil || v.Env.Required == nil || !*v.Env.Required {
+			continue
+		}
+
+		if _, found := lookupValue(v.Env); !found {
+			missing = append(missing, fmt.Sprintf("%s (variable '%s')", v.Env.Variable, v.Name))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required environment variables: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,3 +19,72 @@ func TestGetValue(t *testing.T) {
 
 	assert.Equal(t, "foo", c.GetValue(e))
 }
+
+func TestGetValueDefault(t *testing.T) {
+	os.Unsetenv("TEST_ENV_UNSET")
+	os.Setenv("TEST_ENV_EMPTY", "")
+
+	c := &Client{}
+	e := &schemas.Env{
+		Variable: "TEST_ENV_UNSET",
+		Default:  pointy.String("foo"),
+	}
+	assert.Equal(t, "foo", c.GetValue(e))
+
+	// Empty values fall back onto the default one
+	e.Variable = "TEST_ENV_EMPTY"
+	assert.Equal(t, "foo", c.GetValue(e))
+
+	// Unless they are allowed
+	e.AllowEmpty = pointy.Bool(true)
+	assert.Equal(t, "", c.GetValue(e))
+}
+
+func TestGetVariableValuesRequired(t *testing.T) {
+	os.Unsetenv("TEST_ENV_UNSET")
+	os.Setenv("TEST_ENV_EMPTY", "")
+
+	c := &Client{}
+	v := &schemas.Variable{
+		Name: "foo",
+		Env: &schemas.Env{
+			Variable: "TEST_ENV_UNSET",
+		},
+	}
+
+	// Not required, an empty value is returned
+	values, err := c.GetVariableValues(v)
+	assert.NoError(t, err)
+	assert.Equal(t, "", values[0].Value)
+
+	v.Env.Required = pointy.Bool(true)
+	_, err = c.GetVariableValues(v)
+	assert.EqualError(t, err, "environment variable 'TEST_ENV_UNSET' is not defined (or empty) for variable 'foo'")
+
+	v.Env.Variable = "TEST_ENV_EMPTY"
+	_, err = c.GetVariableValues(v)
+	assert.Error(t, err)
+
+	v.Env.AllowEmpty = pointy.Bool(true)
+	values, err = c.GetVariableValues(v)
+	assert.NoError(t, err)
+	assert.Equal(t, "", values[0].Value)
+}
+
+func TestValidateVariables(t *testing.T) {
+	os.Unsetenv("TEST_ENV_UNSET")
+	os.Unsetenv("TEST_ENV_OTHER_UNSET")
+	os.Setenv("TEST_ENV", "foo")
+
+	c := &Client{}
+	vars := schemas.Variables{
+		{Name: "foo", Env: &schemas.Env{Variable: "TEST_ENV", Required: pointy.Bool(true)}},
+		{Name: "bar", Env: &schemas.Env{Variable: "TEST_ENV_UNSET", Required: pointy.Bool(true)}},
+		{Name: "baz", Env: &schemas.Env{Variable: "TEST_ENV_OTHER_UNSET", Required: pointy.Bool(true)}},
+		{Name: "qux", Env: &schemas.Env{Variable: "TEST_ENV_UNSET"}},
+		{Name: "quux", Env: &schemas.Env{Variable: "TEST_ENV_UNSET", Required: pointy.Bool(true), Default: pointy.String("foo")}},
+	}
+
+	assert.EqualError(t, c.ValidateVariables(vars), "missing required environment variables: TEST_ENV_UNSET (variable 'bar'), TEST_ENV_OTHER_UNSET (variable 'baz')")
+	assert.NoError(t, c.ValidateVariables(vars[3:]))
+}
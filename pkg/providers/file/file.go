@@ -0,0 +1,227 @@
+package file
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/mitchellh/go-homedir"
+	"github.com/mvisonneau/tfcw/pkg/jsonpath"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"gopkg.in/yaml.v3"
+)
+
+// Client is here to support provider related functions
+type Client struct {
+	WorkingDir string
+}
+
+// GetValue returns the content of a file or the value of one of its keys
+func (c *Client) GetValue(f *schemas.File) (string, error) {
+	if f == nil || len(f.Path) == 0 {
+		return "", fmt.Errorf("no path defined")
+	}
+
+	path, err := GetPath(c.WorkingDir, f.Path)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+
+	value := string(content)
+	if f.Key != nil {
+		format := GetFormat(f.Path, f.Format)
+		if value, err = extract(content, format, *f.Key, path); err != nil {
+			return "", fmt.Errorf("unable to extract key '%s' from %s file '%s': %s", *f.Key, format, f.Path, err)
+		}
+	}
+
+	if f.Base64 != nil && *f.Base64 {
+		return base64.StdEncoding.EncodeToString([]byte(value)), nil
+	}
+
+	return value, nil
+}
+
+// GetVariableValues returns the value of a variable from a file
+func (c *Client) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	value, err := c.GetValue(v.File)
+	if err != nil {
+		return nil, fmt.Errorf("error getting value from file for variable '%s' : %s", v.Name, err)
+	}
+
+	return schemas.VariablesWithValues{
+		&schemas.VariableWithValue{
+			Variable: *v,
+			Value:    value,
+		},
+	}, nil
+}
+
+// GetPath returns the path of a file, relative paths are resolved from the working directory
+func GetPath(workingDir, path string) (string, error) {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	return filepath.Join(workingDir, path), nil
+}
+
+// GetFormat returns the configured format or infers it from the extension of the file
+func GetFormat(path string, format *schemas.FileFormatType) schemas.FileFormatType {
+	if format != nil {
+		return *format
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return schemas.FileFormatTypeJSON
+	case ".yaml", ".yml":
+		return schemas.FileFormatTypeYAML
+	case ".env":
+		return schemas.FileFormatTypeDotenv
+	case ".hcl", ".tfvars":
+		return schemas.FileFormatTypeHCL
+	}
+
+	if strings.HasPrefix(filepath.Base(path), ".env") {
+		return schemas.FileFormatTypeDotenv
+	}
+
+	return schemas.FileFormatTypeRaw
+}
+
+func extract(content []byte, format schemas.FileFormatType, key, path string) (string, error) {
+	data, err := Parse(content, format, path)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := jsonpath.Lookup(data, key)
+	if err != nil {
+		return "", err
+	}
+
+	return jsonpath.String(value)
+}
+
+// Parse returns the content of a json, yaml, dotenv or hcl file as a generic structure
+// which can be browsed using jsonpath.Lookup
+func Parse(content []byte, format schemas.FileFormatType, path string) (data interface{}, err error) {
+	switch format {
+	case schemas.FileFormatTypeJSON:
+		d := json.NewDecoder(bytes.NewReader(content))
+		d.UseNumber()
+		if err = d.Decode(&data); err != nil {
+			return nil, fmt.Errorf("invalid json: %s", err)
+		}
+	case schemas.FileFormatTypeYAML:
+		if err = yaml.Unmarshal(content, &data); err != nil {
+			return nil, fmt.Errorf("invalid yaml: %s", err)
+		}
+	case schemas.FileFormatTypeDotenv:
+		var values map[string]string
+		if values, err = parseDotenv(content); err != nil {
+			return
+		}
+
+		m := map[string]interface{}{}
+		for k, v := range values {
+			m[k] = v
+		}
+		data = m
+	case schemas.FileFormatTypeHCL:
+		data, err = parseHCL(content, path)
+	case schemas.FileFormatTypeRaw:
+		err = fmt.Errorf("keys cannot be extracted from raw files, a format must be configured")
+	default:
+		err = fmt.Errorf("unsupported format '%s', must be either json, yaml, dotenv, hcl or raw", format)
+	}
+	return
+}
+
+// parseDotenv parses KEY=VALUE lines, supporting comments, 'export' prefixes and quoted values
+func parseDotenv(content []byte) (map[string]string, error) {
+	values := map[string]string{}
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid dotenv syntax on line %d", i+1)
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			if err := json.Unmarshal([]byte(value), &value); err != nil {
+				return nil, fmt.Errorf("invalid double quoted value on line %d: %s", i+1, err)
+			}
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		default:
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = strings.TrimSpace(value[:j])
+			}
+		}
+
+		values[key] = value
+	}
+	return values, nil
+}
+
+// parseHCL evaluates the attributes of an HCL file without any context
+func parseHCL(content []byte, path string) (interface{}, error) {
+	f, diags := hclparse.NewParser().ParseHCL(content, path)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	attributes, diags := f.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	values := map[string]cty.Value{}
+	for name, attribute := range attributes {
+		var value cty.Value
+		if value, diags = attribute.Expr.Value(&hcl.EvalContext{}); diags.HasErrors() {
+			return nil, diags
+		}
+		values[name] = value
+	}
+
+	object := cty.ObjectVal(values)
+	b, err := ctyjson.Marshal(object, object.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return data, d.Decode(&data)
+}
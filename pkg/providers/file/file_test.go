@@ -0,0 +1,152 @@
+package file
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+var testFiles = map[string]string{
+	"cert.pem": "-----BEGIN CERTIFICATE-----\nfoo\n-----END CERTIFICATE-----\n",
+	"config.json": `{
+  "database": {
+    "hosts": ["db1", "db2"],
+    "port": 5432
+  }
+}`,
+	"config.yml": `
+database:
+  hosts:
+    - db1
+    - db2
+  credentials:
+    username: foo
+`,
+	".env": `
+# comment
+export FOO=bar
+BAR="baz\nqux"
+BAZ='single # quoted'
+QUX=unquoted # with a comment
+`,
+	"terraform.tfvars": `
+region = "eu-west-1"
+tags = {
+  team = "foo"
+}
+`,
+	"secrets.txt": `{"foo":"bar"}`,
+}
+
+func createTestFiles(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "tfcw-test-file")
+	assert.NoError(t, err)
+
+	for name, content := range testFiles {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	return dir
+}
+
+func TestGetValue(t *testing.T) {
+	dir := createTestFiles(t)
+	defer os.RemoveAll(dir)
+
+	c := &Client{WorkingDir: dir}
+
+	tests := []struct {
+		name     string
+		file     *schemas.File
+		expected string
+	}{
+		{"raw", &schemas.File{Path: "cert.pem"}, testFiles["cert.pem"]},
+		{"absolute path", &schemas.File{Path: filepath.Join(dir, "cert.pem")}, testFiles["cert.pem"]},
+		{"base64", &schemas.File{Path: "cert.pem", Base64: pointy.Bool(true)}, base64.StdEncoding.EncodeToString([]byte(testFiles["cert.pem"]))},
+		{"json", &schemas.File{Path: "config.json", Key: pointy.String("database.hosts.1")}, "db2"},
+		{"json number", &schemas.File{Path: "config.json", Key: pointy.String("database.port")}, "5432"},
+		{"json object", &schemas.File{Path: "config.json", Key: pointy.String("database.hosts")}, `["db1","db2"]`},
+		{"yaml", &schemas.File{Path: "config.yml", Key: pointy.String("database.credentials.username")}, "foo"},
+		{"dotenv export", &schemas.File{Path: ".env", Key: pointy.String("FOO")}, "bar"},
+		{"dotenv double quotes", &schemas.File{Path: ".env", Key: pointy.String("BAR")}, "baz\nqux"},
+		{"dotenv single quotes", &schemas.File{Path: ".env", Key: pointy.String("BAZ")}, "single # quoted"},
+		{"dotenv comment", &schemas.File{Path: ".env", Key: pointy.String("QUX")}, "unquoted"},
+		{"hcl", &schemas.File{Path: "terraform.tfvars", Key: pointy.String("tags.team")}, "foo"},
+		{"explicit format", &schemas.File{Path: "secrets.txt", Format: formatPtr(schemas.FileFormatTypeJSON), Key: pointy.String("foo")}, "bar"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := c.GetValue(test.file)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, value)
+		})
+	}
+}
+
+func TestGetValueErrors(t *testing.T) {
+	dir := createTestFiles(t)
+	defer os.RemoveAll(dir)
+
+	c := &Client{WorkingDir: dir}
+
+	tests := []struct {
+		name string
+		file *schemas.File
+	}{
+		{"no path", &schemas.File{}},
+		{"unexistent file", &schemas.File{Path: "foo"}},
+		{"key on raw file", &schemas.File{Path: "cert.pem", Key: pointy.String("foo")}},
+		{"unknown json key", &schemas.File{Path: "config.json", Key: pointy.String("foo")}},
+		{"unknown dotenv key", &schemas.File{Path: ".env", Key: pointy.String("UNKNOWN")}},
+		{"invalid yaml", &schemas.File{Path: "cert.pem", Format: formatPtr(schemas.FileFormatTypeYAML), Key: pointy.String("foo")}},
+		{"invalid hcl", &schemas.File{Path: "cert.pem", Format: formatPtr(schemas.FileFormatTypeHCL), Key: pointy.String("foo")}},
+		{"invalid dotenv", &schemas.File{Path: "config.json", Format: formatPtr(schemas.FileFormatTypeDotenv), Key: pointy.String("foo")}},
+		{"unsupported format", &schemas.File{Path: "cert.pem", Format: formatPtr("foo"), Key: pointy.String("foo")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := c.GetValue(test.file)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGetVariableValues(t *testing.T) {
+	dir := createTestFiles(t)
+	defer os.RemoveAll(dir)
+
+	c := &Client{WorkingDir: dir}
+	values, err := c.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		File: &schemas.File{
+			Path: "config.yml",
+			Key:  pointy.String("database.hosts.0"),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, values, 1)
+	assert.Equal(t, "db1", values[0].Value)
+
+	_, err = c.GetVariableValues(&schemas.Variable{Name: "foo", File: &schemas.File{}})
+	assert.EqualError(t, err, "error getting value from file for variable 'foo' : no path defined")
+}
+
+func TestGetFormat(t *testing.T) {
+	assert.Equal(t, schemas.FileFormatTypeJSON, GetFormat("foo.JSON", nil))
+	assert.Equal(t, schemas.FileFormatTypeYAML, GetFormat("foo.yaml", nil))
+	assert.Equal(t, schemas.FileFormatTypeDotenv, GetFormat("foo/.env.production", nil))
+	assert.Equal(t, schemas.FileFormatTypeHCL, GetFormat("foo.hcl", nil))
+	assert.Equal(t, schemas.FileFormatTypeRaw, GetFormat("foo", nil))
+	assert.Equal(t, schemas.FileFormatTypeYAML, GetFormat("foo.json", formatPtr(schemas.FileFormatTypeYAML)))
+}
+
+func formatPtr(f schemas.FileFormatType) *schemas.FileFormatType {
+	return &f
+}
@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mvisonneau/tfcw/pkg/process"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+const (
+	// ProtocolVersion is the version of the protocol spoken with the plugins
+	ProtocolVersion int = 1
+
+	// DefaultTimeout is the duration after which a plugin execution gets killed
+	DefaultTimeout = 30 * time.Second
+)
+
+// Client is here to support provider related functions
+type Client struct {
+	WorkingDir string
+}
+
+// Request is written in JSON onto the stdin of the plugin
+type Request struct {
+	Version  int               `json:"version"`
+	Variable RequestVariable   `json:"variable"`
+	Config   map[string]string `json:"config"`
+}
+
+// RequestVariable describes the variable the plugin is queried for
+type RequestVariable struct {
+	Name string               `json:"name"`
+	Kind schemas.VariableKind `json:"kind"`
+}
+
+// Response is expected to be written in JSON onto the stdout of the plugin
+type Response struct {
+	Values map[string]string `json:"values"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// GetValues executes the plugin and returns the values it outputs
+func (c *Client) GetValues(v *schemas.Variable) (map[string]string, error) {
+	if v.Plugin == nil || len(v.Plugin.Command) == 0 {
+		return nil, fmt.Errorf("no command defined for the plugin")
+	}
+
+	timeout := DefaultTimeout
+	if v.Plugin.Timeout != nil {
+		var err error
+		if timeout, err = time.ParseDuration(*v.Plugin.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout '%s': %s", *v.Plugin.Timeout, err)
+		}
+	}
+
+	req := Request{
+		Version: ProtocolVersion,
+		Variable: RequestVariable{
+			Name: v.Name,
+			Kind: v.Kind,
+		},
+		Config: map[string]string{},
+	}
+
+	if v.Plugin.Config != nil {
+		req.Config = *v.Plugin.Config
+	}
+
+	stdin, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	if v.Plugin.Args != nil {
+		args = *v.Plugin.Args
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(c.getCommandPath(v.Plugin.Command), args...) // #nosec G204
+	cmd.Dir = c.WorkingDir
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err = process.Run(ctx, cmd); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("plugin '%s' timed out after %s", v.Plugin.Command, timeout.String())
+		}
+		return nil, fmt.Errorf("plugin '%s' execution error: %s (stderr: %s)", v.Plugin.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	resp := Response{}
+	if err = json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("unable to parse the output of plugin '%s': %s", v.Plugin.Command, err)
+	}
+
+	if len(resp.Error) > 0 {
+		return nil, fmt.Errorf("plugin '%s' returned an error: %s", v.Plugin.Command, resp.Error)
+	}
+
+	return resp.Values, nil
+}
+
+// GetVariableValues returns the values of a variable from the output of the plugin, several
+// values are returned when the variable maps multiple keys
+func (c *Client) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	values, err := c.GetValues(v)
+	if err != nil {
+		return nil, fmt.Errorf("error getting values from plugin for variable '%s' : %s", v.Name, err)
+	}
+
+	if v.Plugin.Key != nil && v.Plugin.Keys != nil && len(*v.Plugin.Keys) > 0 {
+		return nil, fmt.Errorf("you can't set both 'key' and 'keys' when using the plugin provider")
+	}
+
+	source := fmt.Sprintf("the output of plugin '%s'", v.Plugin.Command)
+
+	// If no key is defined, we expect the plugin to return a single value
+	if v.Plugin.Key == nil && (v.Plugin.Keys == nil || len(*v.Plugin.Keys) == 0) {
+		if len(values) != 1 {
+			return nil, fmt.Errorf("expected a single value from %s without 'key' or 'keys' being set, got %d", source, len(values))
+		}
+
+		for _, value := range values {
+			return schemas.VariablesWithValues{
+				&schemas.VariableWithValue{
+					Variable: *v,
+					Value:    value,
+				},
+			}, nil
+		}
+	}
+
+	return v.MapValues(values, v.Plugin.Key, v.Plugin.Keys, source)
+}
+
+// GetVariableNames returns the names of the variables mapped through 'keys'
+func (c *Client) GetVariableNames(v *schemas.Variable) (names []string) {
+	if v.Plugin != nil && v.Plugin.Keys != nil {
+		for _, variableName := range *v.Plugin.Keys {
+			names = append(names, variableName)
+		}
+	}
+	return
+}
+
+// getCommandPath resolves relative paths from the working directory, commands
+// without any path separator are looked up in the PATH
+func (c *Client) getCommandPath(command string) string {
+	if strings.ContainsRune(command, filepath.Separator) && !filepath.IsAbs(command) {
+		return filepath.Join(c.WorkingDir, command)
+	}
+	return command
+}
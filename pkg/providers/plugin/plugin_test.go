@@ -0,0 +1,173 @@
+package plugin
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestPlugin(t *testing.T, script string) (dir string) {
+	dir, err := ioutil.TempDir("", "tfcw-test-plugin")
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "plugin.sh"), []byte("#!/bin/sh\n"+script), 0o700)) // #nosec G306
+	return
+}
+
+func TestGetValues(t *testing.T) {
+	dir := createTestPlugin(t, `cat > request.json
+echo '{"values":{"foo":"bar","baz":"qux"}}'
+`)
+	defer os.RemoveAll(dir)
+
+	c := &Client{WorkingDir: dir}
+	v := &schemas.Variable{
+		Name: "foo",
+		Kind: schemas.VariableKindTerraform,
+		Plugin: &schemas.Plugin{
+			Command: "./plugin.sh",
+			Config:  &map[string]string{"path": "secret/foo"},
+		},
+	}
+
+	values, err := c.GetValues(v)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "bar", "baz": "qux"}, values)
+
+	// Validate what has been sent to the plugin
+	b, err := ioutil.ReadFile(filepath.Join(dir, "request.json"))
+	assert.NoError(t, err)
+
+	req := Request{}
+	assert.NoError(t, json.Unmarshal(b, &req))
+	assert.Equal(t, Request{
+		Version: ProtocolVersion,
+		Variable: RequestVariable{
+			Name: "foo",
+			Kind: schemas.VariableKindTerraform,
+		},
+		Config: map[string]string{"path": "secret/foo"},
+	}, req)
+}
+
+func TestGetValuesErrors(t *testing.T) {
+	c := &Client{}
+
+	// Undefined command
+	_, err := c.GetValues(&schemas.Variable{Plugin: &schemas.Plugin{}})
+	assert.EqualError(t, err, "no command defined for the plugin")
+
+	// Invalid timeout
+	_, err = c.GetValues(&schemas.Variable{Plugin: &schemas.Plugin{Command: "true", Timeout: pointy.String("foo")}})
+	assert.Error(t, err)
+
+	// Error returned by the plugin
+	dir := createTestPlugin(t, `echo '{"error":"access denied"}'`)
+	defer os.RemoveAll(dir)
+	c.WorkingDir = dir
+
+	_, err = c.GetValues(&schemas.Variable{Plugin: &schemas.Plugin{Command: "./plugin.sh"}})
+	assert.EqualError(t, err, "plugin './plugin.sh' returned an error: access denied")
+
+	// Failing execution
+	_, err = c.GetValues(&schemas.Variable{Plugin: &schemas.Plugin{Command: "false"}})
+	assert.Error(t, err)
+
+	// Invalid output
+	_, err = c.GetValues(&schemas.Variable{Plugin: &schemas.Plugin{Command: "echo", Args: &[]string{"foo"}}})
+	assert.Error(t, err)
+
+	// Timeout
+	_, err = c.GetValues(&schemas.Variable{Plugin: &schemas.Plugin{Command: "sleep", Args: &[]string{"5"}, Timeout: pointy.String("100ms")}})
+	assert.EqualError(t, err, "plugin 'sleep' timed out after 100ms")
+}
+
+func TestGetValuesTimeoutWithChildren(t *testing.T) {
+	// The sleep is a child of the plugin, it holds the stdout and stderr pipes open
+	dir := createTestPlugin(t, "sleep 4\necho '{\"values\":{\"foo\":\"bar\"}}'\n")
+	defer os.RemoveAll(dir)
+
+	c := &Client{WorkingDir: dir}
+	start := time.Now()
+	_, err := c.GetValues(&schemas.Variable{Plugin: &schemas.Plugin{Command: "./plugin.sh", Timeout: pointy.String("200ms")}})
+	assert.EqualError(t, err, "plugin './plugin.sh' timed out after 200ms")
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+}
+
+func TestGetVariableValues(t *testing.T) {
+	dir := createTestPlugin(t, `echo '{"values":{"foo":"bar","baz":"qux"}}'`)
+	defer os.RemoveAll(dir)
+
+	c := &Client{WorkingDir: dir}
+
+	// Single key
+	values, err := c.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		Plugin: &schemas.Plugin{
+			Command: "./plugin.sh",
+			Key:     pointy.String("baz"),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, values, 1)
+	assert.Equal(t, "foo", values[0].Name)
+	assert.Equal(t, "qux", values[0].Value)
+
+	// Multiple keys
+	v := &schemas.Variable{
+		Name: "_",
+		Plugin: &schemas.Plugin{
+			Command: "./plugin.sh",
+			Keys: &map[string]string{
+				"foo": "FOO",
+				"baz": "BAZ",
+			},
+		},
+	}
+	values, err = c.GetVariableValues(v)
+	assert.NoError(t, err)
+	assert.Len(t, values, 2)
+	assert.ElementsMatch(t, []string{"FOO", "BAZ"}, c.GetVariableNames(v))
+
+	// Unknown key
+	_, err = c.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		Plugin: &schemas.Plugin{
+			Command: "./plugin.sh",
+			Key:     pointy.String("unknown"),
+		},
+	})
+	assert.EqualError(t, err, "key 'unknown' was not found in the output of plugin './plugin.sh'")
+
+	// No key defined whilst several values are returned
+	_, err = c.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		Plugin: &schemas.Plugin{
+			Command: "./plugin.sh",
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestGetVariableValuesSingleValue(t *testing.T) {
+	dir := createTestPlugin(t, `echo '{"values":{"password":"secret"}}'`)
+	defer os.RemoveAll(dir)
+
+	c := &Client{WorkingDir: dir}
+	values, err := c.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		Plugin: &schemas.Plugin{
+			Command: filepath.Join(dir, "plugin.sh"),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, values, 1)
+	assert.Equal(t, "secret", values[0].Value)
+}
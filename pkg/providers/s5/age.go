@@ -0,0 +1,152 @@
+package s5
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"filippo.io/age"
+	"github.com/mitchellh/go-homedir"
+	"github.com/mvisonneau/s5/pkg/cipher"
+	providerFile "github.com/mvisonneau/tfcw/pkg/providers/file"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+// AgeClient is a s5 cipher engine using age X25519 keys
+type AgeClient struct {
+	Identities []age.Identity
+	Recipients []age.Recipient
+}
+
+// NewAgeClient reads the age identities and recipients files and returns a cipher engine,
+// if no recipients file is provided the recipients are derived from the identities
+func NewAgeClient(identitiesPath, recipientsPath string) (*AgeClient, error) {
+	if len(identitiesPath) == 0 && len(recipientsPath) == 0 {
+		return nil, fmt.Errorf("you need to specify the path of an age identities or recipients file")
+	}
+
+	c := &AgeClient{}
+	if len(identitiesPath) > 0 {
+		f, err := openAgeFile(identitiesPath)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading the age identities file: %s", err)
+		}
+		defer f.Close()
+
+		if c.Identities, err = age.ParseIdentities(f); err != nil {
+			return nil, fmt.Errorf("error while parsing the age identities file: %s", err)
+		}
+	}
+
+	if len(recipientsPath) > 0 {
+		f, err := openAgeFile(recipientsPath)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading the age recipients file: %s", err)
+		}
+		defer f.Close()
+
+		if c.Recipients, err = age.ParseRecipients(f); err != nil {
+			return nil, fmt.Errorf("error while parsing the age recipients file: %s", err)
+		}
+	} else {
+		for _, identity := range c.Identities {
+			if i, ok := identity.(*age.X25519Identity); ok {
+				c.Recipients = append(c.Recipients, i.Recipient())
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// Cipher a value using the age recipients
+func (c *AgeClient) Cipher(value string) (string, error) {
+	if len(c.Recipients) == 0 {
+		return "", fmt.Errorf("at least one age recipient is required to cipher values")
+	}
+
+	var out bytes.Buffer
+	w, err := age.Encrypt(&out, c.Recipients...)
+	if err != nil {
+		return "", fmt.Errorf("age error : %s", err)
+	}
+
+	if _, err = io.WriteString(w, value); err != nil {
+		return "", fmt.Errorf("age error : %s", err)
+	}
+
+	if err = w.Close(); err != nil {
+		return "", fmt.Errorf("age error : %s", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(out.Bytes()), nil
+}
+
+// Decipher a value using the age identities
+func (c *AgeClient) Decipher(value string) (string, error) {
+	if len(c.Identities) == 0 {
+		return "", fmt.Errorf("at least one age identity is required to decipher values")
+	}
+
+	d, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("base64decode error : %s - value : %s", err, value)
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(d), c.Identities...)
+	if err != nil {
+		return "", fmt.Errorf("age error : %s", err)
+	}
+
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("age error : %s", err)
+	}
+
+	return string(out), nil
+}
+
+func (c *Client) getCipherEngineAge(v *schemas.S5) (cipher.Engine, error) {
+	var identitiesPath, recipientsPath string
+	var err error
+
+	// Paths defined in the config are relative to the working directory, the ones
+	// defined in the environment to the current directory
+	if v.CipherEngineAge != nil && v.CipherEngineAge.IdentitiesPath != nil {
+		identitiesPath, err = providerFile.GetPath(c.WorkingDir, *v.CipherEngineAge.IdentitiesPath)
+	} else if c.CipherEngineAge != nil && c.CipherEngineAge.IdentitiesPath != nil {
+		identitiesPath, err = providerFile.GetPath(c.WorkingDir, *c.CipherEngineAge.IdentitiesPath)
+	} else {
+		identitiesPath = os.Getenv("S5_AGE_IDENTITIES_PATH")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if v.CipherEngineAge != nil && v.CipherEngineAge.RecipientsPath != nil {
+		recipientsPath, err = providerFile.GetPath(c.WorkingDir, *v.CipherEngineAge.RecipientsPath)
+	} else if c.CipherEngineAge != nil && c.CipherEngineAge.RecipientsPath != nil {
+		recipientsPath, err = providerFile.GetPath(c.WorkingDir, *c.CipherEngineAge.RecipientsPath)
+	} else {
+		recipientsPath = os.Getenv("S5_AGE_RECIPIENTS_PATH")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAgeClient(identitiesPath, recipientsPath)
+}
+
+func openAgeFile(path string) (*os.File, error) {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filepath.Clean(path))
+}
@@ -0,0 +1,139 @@
+package s5
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testAgeIdentity  string = "AGE-SECRET-KEY-1WDJCQ6XZZZCJ4APW0324FGVR46Z2F2MXE6H097V06GK40HLFE5HQ2RMT02"
+	testAgeRecipient string = "age1e5mdg3sz7a2wf4lr83jcng4wysz5vnpru2jjk2wadlttal23vs0sc3hx9n"
+)
+
+func createTestAgeFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile(os.TempDir(), "tfcw-test-age-")
+	assert.NoError(t, err)
+
+	_, err = f.Write([]byte("# created by tfcw tests\n" + content + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	return f.Name()
+}
+
+func TestAgeClient(t *testing.T) {
+	identitiesPath := createTestAgeFile(t, testAgeIdentity)
+	defer os.Remove(identitiesPath)
+
+	recipientsPath := createTestAgeFile(t, testAgeRecipient)
+	defer os.Remove(recipientsPath)
+
+	// Recipients derived from the identities
+	c, err := NewAgeClient(identitiesPath, "")
+	assert.NoError(t, err)
+	assert.Len(t, c.Recipients, 1)
+	assert.Equal(t, testAgeRecipient, c.Recipients[0].(*age.X25519Recipient).String())
+
+	ciphered, err := c.Cipher("foo")
+	assert.NoError(t, err)
+
+	deciphered, err := c.Decipher(ciphered)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", deciphered)
+
+	// Recipients only, ciphering is possible but not deciphering
+	c, err = NewAgeClient("", recipientsPath)
+	assert.NoError(t, err)
+
+	ciphered, err = c.Cipher("bar")
+	assert.NoError(t, err)
+
+	_, err = c.Decipher(ciphered)
+	assert.EqualError(t, err, "at least one age identity is required to decipher values")
+
+	c, err = NewAgeClient(identitiesPath, recipientsPath)
+	assert.NoError(t, err)
+
+	deciphered, err = c.Decipher(ciphered)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", deciphered)
+
+	// Invalid configurations
+	_, err = NewAgeClient("", "")
+	assert.EqualError(t, err, "you need to specify the path of an age identities or recipients file")
+
+	_, err = NewAgeClient("/does/not/exist", "")
+	assert.Error(t, err)
+
+	_, err = NewAgeClient(recipientsPath, "")
+	assert.Error(t, err)
+
+	_, err = c.Decipher("foo")
+	assert.Error(t, err)
+}
+
+func TestGetCipherEngineAge(t *testing.T) {
+	cipherEngineType := schemas.S5CipherEngineTypeAge
+	identitiesPath := createTestAgeFile(t, testAgeIdentity)
+	defer os.Remove(identitiesPath)
+
+	expectedEngine, err := NewAgeClient(identitiesPath, "")
+	assert.NoError(t, err)
+
+	// all defined in client, empty variable config (default settings)
+	v := &schemas.S5{}
+	c := &Client{
+		CipherEngineType: &cipherEngineType,
+		CipherEngineAge: &schemas.S5CipherEngineAge{
+			IdentitiesPath: &identitiesPath,
+		},
+	}
+
+	cipherEngine, err := c.getCipherEngine(v)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedEngine, cipherEngine)
+
+	// all defined in variable, empty client config
+	c = &Client{}
+	v = &schemas.S5{
+		CipherEngineType: &cipherEngineType,
+		CipherEngineAge: &schemas.S5CipherEngineAge{
+			IdentitiesPath: &identitiesPath,
+		},
+	}
+
+	cipherEngine, err = c.getCipherEngine(v)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedEngine, cipherEngine)
+
+	// relative paths are resolved from the working directory
+	c = &Client{WorkingDir: filepath.Dir(identitiesPath)}
+	v = &schemas.S5{
+		CipherEngineType: &cipherEngineType,
+		CipherEngineAge: &schemas.S5CipherEngineAge{
+			IdentitiesPath: pointy.String(filepath.Base(identitiesPath)),
+		},
+	}
+
+	cipherEngine, err = c.getCipherEngine(v)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedEngine, cipherEngine)
+
+	// path defined in environment variable
+	os.Setenv("S5_AGE_IDENTITIES_PATH", identitiesPath)
+	defer os.Unsetenv("S5_AGE_IDENTITIES_PATH")
+	c = &Client{}
+	v = &schemas.S5{
+		CipherEngineType: &cipherEngineType,
+	}
+
+	cipherEngine, err = c.getCipherEngine(v)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedEngine, cipherEngine)
+}
@@ -9,8 +9,11 @@ import (
 
 // Client is here to support provider related functions
 type Client struct {
+	WorkingDir string
+
 	CipherEngineType  *schemas.S5CipherEngineType
 	CipherEngineAES   *schemas.S5CipherEngineAES
+	CipherEngineAge   *schemas.S5CipherEngineAge
 	CipherEngineAWS   *schemas.S5CipherEngineAWS
 	CipherEngineGCP   *schemas.S5CipherEngineGCP
 	CipherEnginePGP   *schemas.S5CipherEnginePGP
@@ -37,6 +40,37 @@ func (c *Client) GetValue(v *schemas.S5) (string, error) {
 	return value, nil
 }
 
+// Cipher returns the value ciphered with the engine resolved for the variable,
+// formatted as an s5 payload
+func (c *Client) Cipher(v *schemas.S5, value string) (string, error) {
+	variableCipher, err := c.getCipherEngine(v)
+	if err != nil {
+		return "", fmt.Errorf("s5 error whilst getting cipher engine: %s", err.Error())
+	}
+
+	ciphered, err := variableCipher.Cipher(value)
+	if err != nil {
+		return "", fmt.Errorf("s5 error whilst ciphering: %s", err.Error())
+	}
+
+	return cipher.GenerateOutput(ciphered), nil
+}
+
+// GetVariableValues returns the deciphered value of a variable
+func (c *Client) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	value, err := c.GetValue(v.S5)
+	if err != nil {
+		return nil, fmt.Errorf("s5 error: %s", err)
+	}
+
+	return schemas.VariablesWithValues{
+		&schemas.VariableWithValue{
+			Variable: *v,
+			Value:    value,
+		},
+	}, nil
+}
+
 func (c *Client) getCipherEngine(v *schemas.S5) (cipher.Engine, error) {
 	var cipherEngineType *schemas.S5CipherEngineType
 	if v.CipherEngineType != nil {
@@ -50,6 +84,8 @@ func (c *Client) getCipherEngine(v *schemas.S5) (cipher.Engine, error) {
 	switch *cipherEngineType {
 	case schemas.S5CipherEngineTypeAES:
 		return c.getCipherEngineAES(v)
+	case schemas.S5CipherEngineTypeAge:
+		return c.getCipherEngineAge(v)
 	case schemas.S5CipherEngineTypeAWS:
 		return c.getCipherEngineAWS(v)
 	case schemas.S5CipherEngineTypeGCP:
@@ -0,0 +1,118 @@
+package sops
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/mvisonneau/tfcw/pkg/jsonpath"
+	providerFile "github.com/mvisonneau/tfcw/pkg/providers/file"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"go.mozilla.org/sops/v3/cmd/sops/formats"
+	"go.mozilla.org/sops/v3/decrypt"
+)
+
+// Client is here to support provider related functions
+type Client struct {
+	WorkingDir string
+}
+
+// GetValues decrypts a SOPS file and returns the values of the requested keys
+func (c *Client) GetValues(s *schemas.SOPS, keys []string) (map[string]string, error) {
+	if s == nil || len(s.Path) == 0 {
+		return nil, fmt.Errorf("no path defined")
+	}
+
+	path, err := providerFile.GetPath(c.WorkingDir, s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	format := providerFile.GetFormat(s.Path, s.Format)
+	sopsFormat, err := getSOPSFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	cleartext, err := decrypt.DataWithFormat(content, sopsFormat)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt '%s': %s", s.Path, err)
+	}
+
+	data, err := providerFile.Parse(cleartext, format, path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for _, key := range keys {
+		value, err := jsonpath.Lookup(data, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if values[key], err = jsonpath.String(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// GetVariableValues returns the values of a variable from a SOPS encrypted file, several
+// values are returned when the variable maps multiple keys
+func (c *Client) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	if v.SOPS == nil {
+		return nil, fmt.Errorf("no sops configuration defined for variable '%s'", v.Name)
+	}
+
+	if (v.SOPS.Key == nil && (v.SOPS.Keys == nil || len(*v.SOPS.Keys) == 0)) ||
+		(v.SOPS.Key != nil && v.SOPS.Keys != nil && len(*v.SOPS.Keys) > 0) {
+		return nil, fmt.Errorf("you either need to set 'key' or 'keys' when using the sops provider")
+	}
+
+	keys := []string{}
+	if v.SOPS.Key != nil {
+		keys = append(keys, *v.SOPS.Key)
+	}
+
+	if v.SOPS.Keys != nil {
+		for key := range *v.SOPS.Keys {
+			keys = append(keys, key)
+		}
+	}
+
+	values, err := c.GetValues(v.SOPS, keys)
+	if err != nil {
+		return nil, fmt.Errorf("error getting values from sops for variable '%s' : %s", v.Name, err)
+	}
+
+	return v.MapValues(values, v.SOPS.Key, v.SOPS.Keys, fmt.Sprintf("sops file '%s'", v.SOPS.Path))
+}
+
+// GetVariableNames returns the names of the variables mapped through 'keys'
+func (c *Client) GetVariableNames(v *schemas.Variable) (names []string) {
+	if v.SOPS != nil && v.SOPS.Keys != nil {
+		for _, variableName := range *v.SOPS.Keys {
+			names = append(names, variableName)
+		}
+	}
+	return
+}
+
+func getSOPSFormat(format schemas.FileFormatType) (formats.Format, error) {
+	switch format {
+	case schemas.FileFormatTypeJSON:
+		return formats.Json, nil
+	case schemas.FileFormatTypeYAML:
+		return formats.Yaml, nil
+	case schemas.FileFormatTypeDotenv:
+		return formats.Dotenv, nil
+	}
+	return formats.Binary, fmt.Errorf("unsupported format '%s', must be either json, yaml or dotenv", format)
+}
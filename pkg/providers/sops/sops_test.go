@@ -0,0 +1,211 @@
+package sops
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+// Fixtures have been encrypted with the following age key
+const testAgeKey = "AGE-SECRET-KEY-1WDJCQ6XZZZCJ4APW0324FGVR46Z2F2MXE6H097V06GK40HLFE5HQ2RMT02"
+
+const testYAML = `database:
+    username: ENC[AES256_GCM,data:D1IM,iv:0caxfOTvgmSLq/aiZzqXVi/51zdvzE9+0qkpkv3NhPw=,tag:b342ifXpfaUR73JL+/JTvg==,type:str]
+    password: ENC[AES256_GCM,data:yxJH,iv:AXX7RnYR9/EnButeyPTGAqdEw0smPK9GOATvTvs3YBE=,tag:7WSkX0D9dEZ2LiSuTzZlSQ==,type:str]
+    port: ENC[AES256_GCM,data:sJxfBQ==,iv:47P4XZaI0ICCTq/EFhUB5Nv6zWXtJJoSJACmug/aBsg=,tag:bm3bOzwWhW65DA1AYYwr2A==,type:int]
+sops:
+    kms: []
+    gcp_kms: []
+    azure_kv: []
+    hc_vault: []
+    age:
+        - recipient: age1e5mdg3sz7a2wf4lr83jcng4wysz5vnpru2jjk2wadlttal23vs0sc3hx9n
+          enc: |
+            -----BEGIN AGE ENCRYPTED FILE-----
+            YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSBiQXduUngyZDI4WXlYbVc5
+            ZmtkeTE2cXNRTWtQWkpidWFDdHNjUG1BUm1VCmFKcTVFVjVISUI0WVRaZ3V6K1lB
+            YXRJcEVBL0ltcC8xSjc0K2NITlpOVHMKLS0tIDlYZVlmWnZuTndCNkkvQkFwVkg1
+            SVFWV0tyeGpmWGthZGJzMUV1UDNrOWMKwaCYpQQboqkkOa68Y+rxIMc1b++wNH5k
+            wE/JohTS+u4nXbVihQzOO8MtHh6l+ojTlAlZesykNUziMAAlzn4vLA==
+            -----END AGE ENCRYPTED FILE-----
+    lastmodified: "2026-10-17T03:10:00Z"
+    mac: ENC[AES256_GCM,data:u1AOfsbr8Bdz80Cwsef7C8YbT20Srz0PCJ21qxpsHMmVELosi/n1SNCE4hpYOlPkrT/VZ3Vc1vLb5/ZK9M8NiXuj2oO8WXk++81RQx+BMtNqWHg9FBlOSP0aFDKJJXV3/hZnQWJVGBbF2kuJ7ArYKZpQhioI1kzgHIuYSkHrS8M=,iv:ieWlpY5kV4yeaXcLh1uM8PEhs2kNYx1ZjlQrD70+2GQ=,tag:DGth3OhIwRes6fs+7w8/2w==,type:str]
+    pgp: []
+    unencrypted_suffix: _unencrypted
+    version: 3.7.3
+`
+
+const testJSON = `{
+	"api_token": "ENC[AES256_GCM,data:qyKG,iv:boj/7yqrhJzXNTn4mTv7BKYYez0oq2oCyPcG8c5lx+o=,tag:DgBEeuMY5Ep2htjPODQyiw==,type:str]",
+	"sops": {
+		"kms": null,
+		"gcp_kms": null,
+		"azure_kv": null,
+		"hc_vault": null,
+		"age": [
+			{
+				"recipient": "age1e5mdg3sz7a2wf4lr83jcng4wysz5vnpru2jjk2wadlttal23vs0sc3hx9n",
+				"enc": "-----BEGIN AGE ENCRYPTED FILE-----\nYWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSArM09pVGpLY0k5SGJhSUhE\naGlyZnpyenVldlZBUUZic2MxZGpSSUhBTDI4CmFMM0pubVQ5WUtqcU93b0lFMlBy\nRnQyTlFtOG8rMmx4d0hTaUJEL2hHMW8KLS0tIFRzQUUvUUlPVXMyZS9UY0x1eGtz\nZDZJdmpEUTdXWUMrOGVnNXR6SjNiRTAK6Ci55H5HJ0Lls48jati87XQj9ZdAqgoH\nujdpWGZciSglnNims82lZwMxNt8mPDNtAV7UKUBi7Nl86pnVkNVs2w==\n-----END AGE ENCRYPTED FILE-----\n"
+			}
+		],
+		"lastmodified": "2026-10-17T03:10:00Z",
+		"mac": "ENC[AES256_GCM,data:Vvwl8GfuBedvIAiCt6QdsZNMTziKRf30mR2eRlV7X0CcFsnTaW2uOQ9wAkbhUEISM+3zhT3T93DXv6vpVItwFdUtjxUIG+POytgoj11BxgNYViv6UG24St/PySgtIIVTRAzkp3hnSSyT1OcuP/WP5wFTOYTVR/K1eD2BbQKRg0w=,iv:DwUkCbEZ2FMqwUf9F3hqvgWJe2tb30NWM5QjUhO9Jgk=,tag:dSiLt1QofZ0zNNRKRQPHXg==,type:str]",
+		"pgp": null,
+		"unencrypted_suffix": "_unencrypted",
+		"version": "3.7.3"
+	}
+}
+`
+
+const testDotenv = `FOO=ENC[AES256_GCM,data:6bpG,iv:VIIEkZF/XE3cbJR5mnitK88EDhDUcGXUzwJ+5uj9yGU=,tag:mEmAEgpZV1dI05BwIGOQ/Q==,type:str]
+BAZ=ENC[AES256_GCM,data:92SN,iv:TOBhflRG84SGDxoAicuehhXrCcvjKGQw8/JY2jPYymQ=,tag:6c56JbvaXXVLCCxWBn+gBw==,type:str]
+sops_age__list_0__map_recipient=age1e5mdg3sz7a2wf4lr83jcng4wysz5vnpru2jjk2wadlttal23vs0sc3hx9n
+sops_mac=ENC[AES256_GCM,data:XNpZ2wucPnicTzMc4AmVyXzXK+1D7WQDDT2JP8ew8wGWyWEwmgY2vkz98+Tope7TVzYKdeJknHLpKWQ2wARr11fjfd3sBcQeQb0U8cJxc6Fde6ifO79YPDM/uzh0ae5a3rC4ownmc1oBEvJXpn+7bF88h6/+IWAnRAMx8rzq/dM=,iv:jYZUumOBRLSS/8RLb0kAuX3OVxGIMul6Q91UI6TWGx4=,tag:Qjl/asXc5ud5QBkoe2SbEw==,type:str]
+sops_unencrypted_suffix=_unencrypted
+sops_version=3.7.3
+sops_lastmodified=2026-10-17T03:10:00Z
+sops_age__list_0__map_enc=-----BEGIN AGE ENCRYPTED FILE-----\nYWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSBLb0tRU1NvbExON2pBaHF2\nRWkrQy90c1RISy9BekFvUVVBMDRtaGNRMkM0ClBQZDFFQWJOeW9yVUR6bmQ1UW1J\ncHc0TGdEVVlJNVNZMDJST050SDNoODgKLS0tIHUxMHVKbDBaNUllV2pybWRlSUVa\nS25ZVU9BTDY5UHlyL1BaT0ZwTE1FK0EKa6L78UwVw+GHF7pOGWETg27sw8cfKVBW\nV6PdQQ35zRbCaHU2KQsmWsEm5dnJUmohp0nm2ZArDrZ7gfP+hq4T6g==\n-----END AGE ENCRYPTED FILE-----\n
+`
+
+func createTestFiles(t *testing.T) (dir string) {
+	dir, err := ioutil.TempDir("", "tfcw-test-sops")
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "secrets.yaml"), []byte(testYAML), 0o600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "secrets.json"), []byte(testJSON), 0o600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".env"), []byte(testDotenv), 0o600))
+	return
+}
+
+func TestGetValues(t *testing.T) {
+	os.Setenv("SOPS_AGE_KEY", testAgeKey)
+	defer os.Unsetenv("SOPS_AGE_KEY")
+
+	dir := createTestFiles(t)
+	defer os.RemoveAll(dir)
+
+	c := &Client{WorkingDir: dir}
+
+	values, err := c.GetValues(&schemas.SOPS{Path: "secrets.yaml"}, []string{"database.username", "database.port"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"database.username": "foo", "database.port": "5432"}, values)
+
+	_, err = c.GetValues(&schemas.SOPS{Path: "secrets.yaml"}, []string{"database.username", "database.unknown"})
+	assert.EqualError(t, err, "key 'unknown' of path 'database.unknown' not found")
+
+	_, err = c.GetValues(&schemas.SOPS{Path: "secrets.yaml"}, []string{"database.port.foo"})
+	assert.EqualError(t, err, "cannot lookup 'foo' of path 'database.port.foo' within a scalar value")
+
+	values, err = c.GetValues(&schemas.SOPS{Path: "secrets.json"}, []string{"api_token"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"api_token": "baz"}, values)
+
+	values, err = c.GetValues(&schemas.SOPS{Path: ".env"}, []string{"FOO", "BAZ"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"FOO": "bar", "BAZ": "qux"}, values)
+}
+
+func TestGetValuesErrors(t *testing.T) {
+	dir := createTestFiles(t)
+	defer os.RemoveAll(dir)
+
+	c := &Client{WorkingDir: dir}
+
+	// Undefined path
+	_, err := c.GetValues(&schemas.SOPS{}, nil)
+	assert.EqualError(t, err, "no path defined")
+
+	// Unsupported format
+	format := schemas.FileFormatTypeHCL
+	_, err = c.GetValues(&schemas.SOPS{Path: "secrets.yaml", Format: &format}, nil)
+	assert.EqualError(t, err, "unsupported format 'hcl', must be either json, yaml or dotenv")
+
+	// No key available to decrypt the file
+	os.Unsetenv("SOPS_AGE_KEY")
+	_, err = c.GetValues(&schemas.SOPS{Path: "secrets.yaml"}, nil)
+	assert.Error(t, err)
+}
+
+func TestGetVariableValues(t *testing.T) {
+	os.Setenv("SOPS_AGE_KEY", testAgeKey)
+	defer os.Unsetenv("SOPS_AGE_KEY")
+
+	dir := createTestFiles(t)
+	defer os.RemoveAll(dir)
+
+	c := &Client{WorkingDir: dir}
+
+	// Single key
+	values, err := c.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		SOPS: &schemas.SOPS{
+			Path: "secrets.yaml",
+			Key:  pointy.String("database.password"),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, values, 1)
+	assert.Equal(t, "foo", values[0].Name)
+	assert.Equal(t, "bar", values[0].Value)
+
+	// Multiple keys
+	v := &schemas.Variable{
+		Name: "_",
+		SOPS: &schemas.SOPS{
+			Path: ".env",
+			Keys: &map[string]string{
+				"FOO": "foo",
+				"BAZ": "baz",
+			},
+		},
+	}
+	values, err = c.GetVariableValues(v)
+	assert.NoError(t, err)
+	assert.Len(t, values, 2)
+	assert.ElementsMatch(t, []string{"foo", "baz"}, c.GetVariableNames(v))
+
+	// Unknown key
+	_, err = c.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		SOPS: &schemas.SOPS{
+			Path: "secrets.json",
+			Key:  pointy.String("unknown"),
+		},
+	})
+	assert.EqualError(t, err, "error getting values from sops for variable 'foo' : key 'unknown' of path 'unknown' not found")
+
+	// No key defined
+	_, err = c.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		SOPS: &schemas.SOPS{
+			Path: "secrets.json",
+		},
+	})
+	assert.EqualError(t, err, "you either need to set 'key' or 'keys' when using the sops provider")
+
+	// Empty keys
+	_, err = c.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		SOPS: &schemas.SOPS{
+			Path: "secrets.json",
+			Keys: &map[string]string{},
+		},
+	})
+	assert.EqualError(t, err, "you either need to set 'key' or 'keys' when using the sops provider")
+
+	// Both key and keys defined
+	_, err = c.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		SOPS: &schemas.SOPS{
+			Path: "secrets.json",
+			Key:  pointy.String("api_token"),
+			Keys: &map[string]string{"api_token": "bar"},
+		},
+	})
+	assert.EqualError(t, err, "you either need to set 'key' or 'keys' when using the sops provider")
+}
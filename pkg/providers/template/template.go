@@ -0,0 +1,221 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/mvisonneau/tfcw/pkg/functions"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// Resolver returns the value of a variable given its kind and name
+type Resolver func(kind schemas.VariableKind, name string) (string, error)
+
+// Reference is a variable referenced within a template
+type Reference struct {
+	Kind schemas.VariableKind
+	Name string
+}
+
+// String returns the reference as it is written in templates
+func (r Reference) String() string {
+	return fmt.Sprintf("%s.%s", getRoot(r.Kind), r.Name)
+}
+
+// Client is here to support provider related functions, Resolve
+// is used to obtain the values of the referenced variables
+type Client struct {
+	Resolve Resolver
+}
+
+var roots = map[string]schemas.VariableKind{
+	"tfvar":  schemas.VariableKindTerraform,
+	"envvar": schemas.VariableKindEnvironment,
+}
+
+func getRoot(kind schemas.VariableKind) string {
+	for root, k := range roots {
+		if k == kind {
+			return root
+		}
+	}
+	return string(kind)
+}
+
+// GetReferences returns the variables referenced within a template
+func GetReferences(t *schemas.Template) (references []Reference, err error) {
+	if t == nil || t.Content == nil {
+		return nil, fmt.Errorf("no content defined")
+	}
+
+	for _, traversal := range t.Content.Variables() {
+		kind, ok := roots[traversal.RootName()]
+		if !ok {
+			return nil, fmt.Errorf("unknown reference '%s', expected tfvar.<name> or envvar.<name>", traversal.RootName())
+		}
+
+		if len(traversal) < 2 {
+			return nil, fmt.Errorf("missing variable name after '%s', expected %s.<name>", traversal.RootName(), traversal.RootName())
+		}
+
+		var name string
+		switch step := traversal[1].(type) {
+		case hcl.TraverseAttr:
+			name = step.Name
+		case hcl.TraverseIndex:
+			if step.Key.Type() != cty.String {
+				return nil, fmt.Errorf("invalid reference to a %s, the name of the variable must be a string", traversal.RootName())
+			}
+			name = step.Key.AsString()
+		default:
+			return nil, fmt.Errorf("invalid reference to a %s, expected %s.<name>", traversal.RootName(), traversal.RootName())
+		}
+
+		references = append(references, Reference{Kind: kind, Name: name})
+	}
+	return
+}
+
+// GetValue renders a template using the values of the variables it references
+func (c *Client) GetValue(t *schemas.Template) (string, error) {
+	value, err := c.getValue(t)
+	if err != nil {
+		return "", err
+	}
+
+	if value, err = convert.Convert(value, cty.String); err != nil {
+		return "", fmt.Errorf("template must render a string : %s", err)
+	}
+
+	return value.AsString(), nil
+}
+
+// getValue evaluates a template, which can render any HCL type
+func (c *Client) getValue(t *schemas.Template) (cty.Value, error) {
+	references, err := GetReferences(t)
+	if err != nil {
+		return cty.NilVal, err
+	}
+
+	if len(references) > 0 && c.Resolve == nil {
+		return cty.NilVal, fmt.Errorf("unable to resolve the referenced variables")
+	}
+
+	values := map[string]map[string]cty.Value{}
+	for root := range roots {
+		values[root] = map[string]cty.Value{}
+	}
+
+	for _, r := range references {
+		value, err := c.Resolve(r.Kind, r.Name)
+		if err != nil {
+			return cty.NilVal, fmt.Errorf("unable to resolve %s : %s", r, err)
+		}
+		values[getRoot(r.Kind)][r.Name] = cty.StringVal(value)
+	}
+
+	evalCtx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{},
+		Functions: map[string]function.Function{
+			"env": functions.EnvFunction,
+		},
+	}
+
+	for root, v := range values {
+		evalCtx.Variables[root] = cty.ObjectVal(v)
+	}
+
+	value, diags := t.Content.Value(evalCtx)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+
+	if value.IsNull() {
+		return cty.NilVal, fmt.Errorf("template rendered a null value")
+	}
+
+	return value, nil
+}
+
+// GetVariableValues returns the value of a variable by rendering its template, templates
+// rendering lists, maps or objects produce typed values
+func (c *Client) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	value, err := c.getValue(v.Template)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering the template of variable '%s' : %s", v.Name, err)
+	}
+
+	vv, err := schemas.NewVariableWithCtyValue(v, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return schemas.VariablesWithValues{vv}, nil
+}
+
+// ValidateVariables ensures that the templates only contain valid references
+// and that they do not reference each other in a circular fashion
+func (c *Client) ValidateVariables(vars schemas.Variables) error {
+	templates := map[Reference][]Reference{}
+	for _, v := range vars {
+		if v.Template == nil {
+			continue
+		}
+
+		references, err := GetReferences(v.Template)
+		if err != nil {
+			return fmt.Errorf("invalid template for variable '%s' : %s", v.Name, err)
+		}
+		templates[Reference{Kind: v.Kind, Name: v.Name}] = references
+	}
+
+	visited := map[Reference]bool{}
+	for _, v := range vars {
+		if v.Template == nil {
+			continue
+		}
+
+		if path := findCycle(templates, Reference{Kind: v.Kind, Name: v.Name}, visited, []Reference{}); path != nil {
+			chain := []string{}
+			for _, r := range path {
+				chain = append(chain, r.String())
+			}
+			return fmt.Errorf("circular reference between templates : %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	return nil
+}
+
+// findCycle walks the references of the templates depth-first and returns
+// the path leading to a template being referenced by itself, if any
+func findCycle(templates map[Reference][]Reference, r Reference, visited map[Reference]bool, path []Reference) []Reference {
+	for i, p := range path {
+		if p == r {
+			return append(path[i:], r)
+		}
+	}
+
+	if visited[r] {
+		return nil
+	}
+
+	references, isTemplate := templates[r]
+	if !isTemplate {
+		return nil
+	}
+
+	path = append(path, r)
+	for _, reference := range references {
+		if cycle := findCycle(templates, reference, visited, path); cycle != nil {
+			return cycle
+		}
+	}
+
+	visited[r] = true
+	return nil
+}
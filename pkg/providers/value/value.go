@@ -0,0 +1,25 @@
+package value
+
+import (
+	"fmt"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+// Client is a basic struct in order to support provider
+// related functions
+type Client struct{}
+
+// GetVariableValues returns the literal value of a variable
+func (c *Client) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	if v.Literal == nil {
+		return nil, fmt.Errorf("no value defined for variable '%s'", v.Name)
+	}
+
+	vv, err := schemas.NewVariableWithCtyValue(v, v.Literal.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	return schemas.VariablesWithValues{vv}, nil
+}
@@ -0,0 +1,90 @@
+package value
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestGetVariableValues(t *testing.T) {
+	c := &Client{}
+	values, err := c.GetVariableValues(&schemas.Variable{
+		Name:    "foo",
+		Literal: &schemas.Literal{Value: cty.StringVal("bar")},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, values, 1)
+	assert.Equal(t, "foo", values[0].Name)
+	assert.Equal(t, "bar", values[0].Value)
+	assert.Nil(t, values[0].HCL)
+
+	_, err = c.GetVariableValues(&schemas.Variable{Name: "foo"})
+	assert.EqualError(t, err, "no value defined for variable 'foo'")
+
+	_, err = c.GetVariableValues(&schemas.Variable{Name: "foo", Literal: &schemas.Literal{Value: cty.NullVal(cty.String)}})
+	assert.EqualError(t, err, "the value of variable 'foo' cannot be null or unknown")
+}
+
+func TestGetVariableValuesTyped(t *testing.T) {
+	cfg := &schemas.Config{}
+	assert.NoError(t, hclsimple.Decode("tfcw.hcl", []byte(`
+tfvar "number" {
+  value {
+    value = 5432
+  }
+}
+
+tfvar "list" {
+  value {
+    value = ["a", "b"]
+  }
+}
+
+tfvar "object" {
+  value {
+    value = {
+      foo = "bar"
+      baz = [1, 2]
+    }
+  }
+}
+
+tfvar "explicit" {
+  hcl = false
+  value {
+    value = {
+      foo = "bar"
+      baz = [1, 2]
+    }
+  }
+}
+
+envvar "LIST" {
+  value {
+    value = ["a", "b"]
+  }
+}
+`), nil, cfg))
+	vars := cfg.GetVariables()
+
+	c := &Client{}
+	for i, expected := range []struct {
+		value string
+		hcl   *bool
+	}{
+		{"5432", nil},
+		{`["a", "b"]`, pointy.Bool(true)},
+		{"{\n  baz = [1, 2]\n  foo = \"bar\"\n}", pointy.Bool(true)},
+		{`{"baz":[1,2],"foo":"bar"}`, pointy.Bool(false)},
+		{`["a","b"]`, nil},
+	} {
+		values, err := c.GetVariableValues(vars[i])
+		assert.NoError(t, err)
+		assert.Equal(t, expected.value, values[0].Value, vars[i].Name)
+		assert.Equal(t, expected.hcl, values[0].HCL, vars[i].Name)
+	}
+}
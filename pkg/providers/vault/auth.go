@@ -0,0 +1,124 @@
+package vault
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/go-homedir"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultKubernetesJWTPath is where the service account token is mounted within Kubernetes pods
+const DefaultKubernetesJWTPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Login authenticates against Vault using the configured auth method and
+// sets the returned token onto the client
+func (c *Client) Login(auth *schemas.VaultAuth) error {
+	mount := string(auth.Method)
+	if auth.Mount != nil {
+		mount = strings.Trim(*auth.Mount, "/")
+	}
+
+	path := fmt.Sprintf("auth/%s/login", mount)
+	data := map[string]interface{}{}
+
+	switch auth.Method {
+	case schemas.VaultAuthMethodAppRole:
+		if auth.RoleID == nil {
+			return fmt.Errorf("'role-id' is required when using the approle auth method")
+		}
+
+		data["role_id"] = *auth.RoleID
+		if auth.SecretID != nil {
+			data["secret_id"] = *auth.SecretID
+		}
+	case schemas.VaultAuthMethodKubernetes, schemas.VaultAuthMethodJWT:
+		if auth.Role == nil {
+			return fmt.Errorf("'role' is required when using the %s auth method", auth.Method)
+		}
+
+		jwt, err := getJWT(auth)
+		if err != nil {
+			return err
+		}
+
+		data["role"] = *auth.Role
+		data["jwt"] = jwt
+	case schemas.VaultAuthMethodUserpass:
+		if auth.Username == nil || auth.Password == nil {
+			return fmt.Errorf("'username' and 'password' are required when using the userpass auth method")
+		}
+
+		path = fmt.Sprintf("%s/%s", path, *auth.Username)
+		data["password"] = *auth.Password
+	case schemas.VaultAuthMethodCert:
+		if auth.Role != nil {
+			data["name"] = *auth.Role
+		}
+	default:
+		return fmt.Errorf("unsupported auth method '%s', must be either approle, kubernetes, jwt, userpass or cert", auth.Method)
+	}
+
+	log.Debugf("logging in onto Vault using the %s auth method mounted at '%s'", auth.Method, mount)
+
+	// Make sure we do not send a token which could have been picked up from the environment
+	c.ClearToken()
+	secret, err := c.Logical().Write(path, data)
+	if err != nil {
+		return fmt.Errorf("vault %s login error : %s", auth.Method, err)
+	}
+
+	if secret == nil || secret.Auth == nil || len(secret.Auth.ClientToken) == 0 {
+		return fmt.Errorf("vault %s login error : no token returned", auth.Method)
+	}
+
+	c.SetToken(secret.Auth.ClientToken)
+	return nil
+}
+
+func getJWT(auth *schemas.VaultAuth) (string, error) {
+	if auth.JWT != nil {
+		return *auth.JWT, nil
+	}
+
+	path := DefaultKubernetesJWTPath
+	if auth.JWTPath != nil {
+		path = *auth.JWTPath
+	} else if auth.Method == schemas.VaultAuthMethodJWT {
+		return "", fmt.Errorf("either 'jwt' or 'jwt-path' is required when using the jwt auth method")
+	}
+
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return "", err
+	}
+
+	jwt, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("unable to read the jwt from '%s': %s", path, err)
+	}
+
+	return strings.TrimSpace(string(jwt)), nil
+}
+
+// getTLSConfig returns the client certificate to use for the cert auth method
+func getTLSConfig(auth *schemas.VaultAuth) *api.TLSConfig {
+	if auth == nil || auth.Method != schemas.VaultAuthMethodCert || (auth.ClientCert == nil && auth.ClientKey == nil) {
+		return nil
+	}
+
+	t := &api.TLSConfig{}
+	if auth.ClientCert != nil {
+		t.ClientCert = *auth.ClientCert
+	}
+
+	if auth.ClientKey != nil {
+		t.ClientKey = *auth.ClientKey
+	}
+
+	return t
+}
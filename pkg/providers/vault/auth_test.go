@@ -0,0 +1,150 @@
+// There seems to be a bug in a lib importer by hashicorp/vault/api that prevents the test from running
+// correctly on darwin..
+//
+//go:build !darwin
+// +build !darwin
+
+package vault
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+type testVaultLogin struct {
+	Path          string
+	Token         string
+	Namespace     string
+	Data          map[string]interface{}
+	ReadNamespace string
+}
+
+// createTestVaultServer returns a stub Vault server accepting any login and serving
+// the 'secret/foo' secret to the token it issued
+func createTestVaultServer(t *testing.T) (*httptest.Server, *testVaultLogin) {
+	login := &testVaultLogin{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/secret/foo":
+			login.ReadNamespace = r.Header.Get("X-Vault-Namespace")
+			if r.Header.Get("X-Vault-Token") != "s.issued" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"secret":"bar"}}`))
+		case r.Method == http.MethodPut || r.Method == http.MethodPost:
+			login.Path = r.URL.Path
+			login.Token = r.Header.Get("X-Vault-Token")
+			login.Namespace = r.Header.Get("X-Vault-Namespace")
+			login.Data = map[string]interface{}{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&login.Data))
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"s.issued"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})), login
+}
+
+func TestGetClientWithAuth(t *testing.T) {
+	server, login := createTestVaultServer(t)
+	defer server.Close()
+
+	os.Setenv("VAULT_TOKEN", "from-env")
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	c, err := GetClient(server.URL, "", "", &schemas.VaultAuth{
+		Method:   schemas.VaultAuthMethodAppRole,
+		RoleID:   pointy.String("foo"),
+		SecretID: pointy.String("bar"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "s.issued", c.Token())
+	assert.Equal(t, "/v1/auth/approle/login", login.Path)
+	assert.Equal(t, "", login.Token)
+	assert.Equal(t, map[string]interface{}{"role_id": "foo", "secret_id": "bar"}, login.Data)
+
+	// The issued token is used to fetch the values
+	values, err := c.GetValues(&schemas.Vault{Path: pointy.String("secret/foo")})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"secret": "bar"}, values)
+
+	// A static token takes precedence over the auth method
+	c, err = GetClient(server.URL, "static", "", &schemas.VaultAuth{Method: schemas.VaultAuthMethodAppRole})
+	assert.NoError(t, err)
+	assert.Equal(t, "static", c.Token())
+}
+
+func TestLogin(t *testing.T) {
+	server, login := createTestVaultServer(t)
+	defer server.Close()
+
+	c, err := GetClient(server.URL, "_", "", nil)
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "tfcw-test-vault-auth")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	jwtPath := filepath.Join(dir, "token")
+	assert.NoError(t, ioutil.WriteFile(jwtPath, []byte("eyJfoo\n"), 0o600))
+
+	// Kubernetes
+	assert.NoError(t, c.Login(&schemas.VaultAuth{
+		Method:  schemas.VaultAuthMethodKubernetes,
+		Mount:   pointy.String("/k8s/prod/"),
+		Role:    pointy.String("tfcw"),
+		JWTPath: &jwtPath,
+	}))
+	assert.Equal(t, "/v1/auth/k8s/prod/login", login.Path)
+	assert.Equal(t, map[string]interface{}{"role": "tfcw", "jwt": "eyJfoo"}, login.Data)
+
+	// JWT
+	assert.NoError(t, c.Login(&schemas.VaultAuth{
+		Method: schemas.VaultAuthMethodJWT,
+		Role:   pointy.String("tfcw"),
+		JWT:    pointy.String("eyJbar"),
+	}))
+	assert.Equal(t, "/v1/auth/jwt/login", login.Path)
+	assert.Equal(t, map[string]interface{}{"role": "tfcw", "jwt": "eyJbar"}, login.Data)
+
+	// Userpass
+	assert.NoError(t, c.Login(&schemas.VaultAuth{
+		Method:   schemas.VaultAuthMethodUserpass,
+		Username: pointy.String("foo"),
+		Password: pointy.String("bar"),
+	}))
+	assert.Equal(t, "/v1/auth/userpass/login/foo", login.Path)
+	assert.Equal(t, map[string]interface{}{"password": "bar"}, login.Data)
+
+	// Cert
+	assert.NoError(t, c.Login(&schemas.VaultAuth{
+		Method: schemas.VaultAuthMethodCert,
+		Role:   pointy.String("tfcw"),
+	}))
+	assert.Equal(t, "/v1/auth/cert/login", login.Path)
+	assert.Equal(t, map[string]interface{}{"name": "tfcw"}, login.Data)
+	assert.Equal(t, "s.issued", c.Token())
+}
+
+func TestLoginErrors(t *testing.T) {
+	c, err := GetClient("http://127.0.0.1:0", "_", "", nil)
+	assert.NoError(t, err)
+	c.SetMaxRetries(0)
+
+	assert.EqualError(t, c.Login(&schemas.VaultAuth{Method: "foo"}), "unsupported auth method 'foo', must be either approle, kubernetes, jwt, userpass or cert")
+	assert.EqualError(t, c.Login(&schemas.VaultAuth{Method: schemas.VaultAuthMethodAppRole}), "'role-id' is required when using the approle auth method")
+	assert.EqualError(t, c.Login(&schemas.VaultAuth{Method: schemas.VaultAuthMethodKubernetes}), "'role' is required when using the kubernetes auth method")
+	assert.EqualError(t, c.Login(&schemas.VaultAuth{Method: schemas.VaultAuthMethodJWT, Role: pointy.String("foo")}), "either 'jwt' or 'jwt-path' is required when using the jwt auth method")
+	assert.EqualError(t, c.Login(&schemas.VaultAuth{Method: schemas.VaultAuthMethodUserpass}), "'username' and 'password' are required when using the userpass auth method")
+	assert.Error(t, c.Login(&schemas.VaultAuth{Method: schemas.VaultAuthMethodAppRole, RoleID: pointy.String("foo")}))
+}
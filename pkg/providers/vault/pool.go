@@ -0,0 +1,127 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+// Pool holds the Vault clients, one per address and credentials pair. Variables can
+// override the address and credentials configured in the defaults
+type Pool struct {
+	Defaults *schemas.Vault
+
+	mutex   sync.Mutex
+	clients map[string]*Client
+}
+
+// NewPool returns a pool of Vault clients using the provided defaults
+func NewPool(defaults *schemas.Vault) *Pool {
+	if defaults == nil {
+		defaults = &schemas.Vault{}
+	}
+
+	return &Pool{
+		Defaults: defaults,
+		clients:  map[string]*Client{},
+	}
+}
+
+// GetClient returns the client matching the address and credentials of the
+// variable, it is created (and logged in) the first time it is requested
+func (p *Pool) GetClient(v *schemas.Vault) (*Client, error) {
+	var address, token, namespace string
+	var auth *schemas.VaultAuth
+
+	if v != nil && v.Address != nil {
+		address = *v.Address
+	} else if p.Defaults.Address != nil {
+		address = *p.Defaults.Address
+	}
+
+	if p.Defaults.Namespace != nil {
+		namespace = *p.Defaults.Namespace
+	}
+
+	// Credentials of the variable take precedence over the defaults as a whole,
+	// the login is then performed within the namespace of the variable
+	switch {
+	case v != nil && v.Token != nil:
+		token = *v.Token
+	case v != nil && v.Auth != nil:
+		auth = v.Auth
+		if v.Namespace != nil {
+			namespace = *v.Namespace
+		}
+	case p.Defaults.Token != nil:
+		token = *p.Defaults.Token
+	default:
+		auth = p.Defaults.Auth
+	}
+
+	key, err := getClientKey(address, token, namespace, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if c, ok := p.clients[key]; ok {
+		return c, nil
+	}
+
+	c, err := GetClient(address, token, namespace, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	p.clients[key] = c
+	return c, nil
+}
+
+// GetVariableValues returns the values of a variable using the client matching its configuration
+func (p *Pool) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	c, err := p.GetClient(v.Vault)
+	if err != nil {
+		return nil, fmt.Errorf("error getting vault client for variable '%s' : %s", v.Name, err)
+	}
+
+	return c.GetVariableValues(v)
+}
+
+// RevokeLease revokes a lease obtained whilst fetching the values of a variable, using
+// the client matching its configuration
+func (p *Pool) RevokeLease(v *schemas.Variable, leaseID string) error {
+	c, err := p.GetClient(v.Vault)
+	if err != nil {
+		return fmt.Errorf("error getting vault client for variable '%s' : %s", v.Name, err)
+	}
+
+	return c.RevokeLease(v.Vault, leaseID)
+}
+
+// GetVariableNames returns the names of the variables mapped through 'keys'
+func (p *Pool) GetVariableNames(v *schemas.Variable) []string {
+	return (&Client{}).GetVariableNames(v)
+}
+
+// getClientKey avoids keeping the credentials in clear as keys of the pool
+func getClientKey(address, token, namespace string, auth *schemas.VaultAuth) (string, error) {
+	b, err := json.Marshal(struct {
+		Address   string
+		Token     string
+		Namespace string
+		Auth      *schemas.VaultAuth
+	}{address, token, namespace, auth})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
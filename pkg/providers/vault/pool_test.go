@@ -0,0 +1,132 @@
+// There seems to be a bug in a lib importer by hashicorp/vault/api that prevents the test from running
+// correctly on darwin..
+//
+//go:build !darwin
+// +build !darwin
+
+package vault
+
+import (
+	"testing"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolGetClient(t *testing.T) {
+	shared, sharedLogin := createTestVaultServer(t)
+	defer shared.Close()
+
+	team, _ := createTestVaultServer(t)
+	defer team.Close()
+
+	p := NewPool(&schemas.Vault{
+		Address: pointy.String(shared.URL),
+		Auth: &schemas.VaultAuth{
+			Method: schemas.VaultAuthMethodAppRole,
+			RoleID: pointy.String("foo"),
+		},
+	})
+
+	// Defaults
+	c1, err := p.GetClient(&schemas.Vault{})
+	assert.NoError(t, err)
+	assert.Equal(t, shared.URL, c1.Address())
+	assert.Equal(t, "s.issued", c1.Token())
+
+	// The client is reused, without logging in again
+	sharedLogin.Path = ""
+	c2, err := p.GetClient(&schemas.Vault{Path: pointy.String("secret/foo")})
+	assert.NoError(t, err)
+	assert.Same(t, c1, c2)
+	assert.Equal(t, "", sharedLogin.Path)
+
+	// Overridden address, default credentials
+	c3, err := p.GetClient(&schemas.Vault{Address: pointy.String(team.URL)})
+	assert.NoError(t, err)
+	assert.NotSame(t, c1, c3)
+	assert.Equal(t, team.URL, c3.Address())
+	assert.Equal(t, "s.issued", c3.Token())
+
+	// Overridden token
+	c4, err := p.GetClient(&schemas.Vault{Token: pointy.String("s.team")})
+	assert.NoError(t, err)
+	assert.Equal(t, shared.URL, c4.Address())
+	assert.Equal(t, "s.team", c4.Token())
+	assert.Len(t, p.clients, 3)
+}
+
+func TestPoolGetVariableValues(t *testing.T) {
+	server, _ := createTestVaultServer(t)
+	defer server.Close()
+
+	p := NewPool(nil)
+	values, err := p.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		Vault: &schemas.Vault{
+			Address: pointy.String(server.URL),
+			Token:   pointy.String("s.issued"),
+			Path:    pointy.String("secret/foo"),
+			Key:     pointy.String("secret"),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, values, 1)
+	assert.Equal(t, "bar", values[0].Value)
+
+	_, err = p.GetVariableValues(&schemas.Variable{
+		Name: "foo",
+		Vault: &schemas.Vault{
+			Address: pointy.String(server.URL),
+			Auth:    &schemas.VaultAuth{Method: "foo"},
+		},
+	})
+	assert.EqualError(t, err, "error getting vault client for variable 'foo' : unsupported auth method 'foo', must be either approle, kubernetes, jwt, userpass or cert")
+}
+
+func TestPoolNamespaces(t *testing.T) {
+	server, login := createTestVaultServer(t)
+	defer server.Close()
+
+	p := NewPool(&schemas.Vault{
+		Address:   pointy.String(server.URL),
+		Namespace: pointy.String("bu1"),
+		Auth: &schemas.VaultAuth{
+			Method: schemas.VaultAuthMethodAppRole,
+			RoleID: pointy.String("foo"),
+		},
+	})
+
+	// Login and read within the default namespace
+	v := &schemas.Variable{
+		Name: "foo",
+		Vault: &schemas.Vault{
+			Path: pointy.String("secret/foo"),
+			Key:  pointy.String("secret"),
+		},
+	}
+
+	_, err := p.GetVariableValues(v)
+	assert.NoError(t, err)
+	assert.Equal(t, "bu1", login.Namespace)
+	assert.Equal(t, "bu1", login.ReadNamespace)
+
+	// Read within the namespace of the variable, using the same client
+	v.Vault.Namespace = pointy.String("bu1/team")
+	_, err = p.GetVariableValues(v)
+	assert.NoError(t, err)
+	assert.Equal(t, "bu1/team", login.ReadNamespace)
+	assert.Len(t, p.clients, 1)
+
+	// Login within the namespace of the variable when it defines its own credentials
+	v.Vault.Auth = &schemas.VaultAuth{
+		Method: schemas.VaultAuthMethodAppRole,
+		RoleID: pointy.String("bar"),
+	}
+	_, err = p.GetVariableValues(v)
+	assert.NoError(t, err)
+	assert.Equal(t, "bu1/team", login.Namespace)
+	assert.Equal(t, "bu1/team", login.ReadNamespace)
+	assert.Len(t, p.clients, 2)
+}
@@ -5,10 +5,16 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/mitchellh/go-homedir"
+	"github.com/mvisonneau/tfcw/pkg/jsonpath"
 	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
 // Client is here to support provider related functions
@@ -16,12 +22,22 @@ type Client struct {
 	*api.Client
 }
 
-// GetClient : Get a Vault client using Vault official params
-func GetClient(address, token string) (*Client, error) {
-	c, err := api.NewClient(nil)
+// GetClient : Get a Vault client using Vault official params, if an auth method is
+// configured and no token is provided, the client logs in using it. The namespace, if
+// provided, is used for the login and as the default one for the requests
+func GetClient(address, token, namespace string, auth *schemas.VaultAuth) (*Client, error) {
+	config := api.DefaultConfig()
+	if t := getTLSConfig(auth); t != nil {
+		if err := config.ConfigureTLS(t); err != nil {
+			return nil, fmt.Errorf("Error configuring Vault client certificate: %s", err.Error())
+		}
+	}
+
+	apiClient, err := api.NewClient(config)
 	if err != nil {
 		return nil, fmt.Errorf("Error creating Vault client: %s", err.Error())
 	}
+	c := &Client{apiClient}
 
 	if len(address) > 0 {
 		if err = c.SetAddress(address); err != nil {
@@ -35,8 +51,16 @@ func GetClient(address, token string) (*Client, error) {
 		return nil, fmt.Errorf("Vault address is not defined")
 	}
 
+	if len(namespace) > 0 {
+		c.SetNamespace(namespace)
+	}
+
 	if len(token) > 0 {
 		c.SetToken(token)
+	} else if auth != nil {
+		if err = c.Login(auth); err != nil {
+			return nil, err
+		}
 	} else {
 		token := os.Getenv("VAULT_TOKEN")
 		if len(token) == 0 {
@@ -53,23 +77,46 @@ func GetClient(address, token string) (*Client, error) {
 		c.SetToken(token)
 	}
 
-	return &Client{c}, nil
+	return c, nil
 }
 
-// GetValues returns values from Vault
+// GetValues returns values from Vault, non-string values are converted to JSON
 func (c *Client) GetValues(v *schemas.Vault) (results map[string]string, err error) {
+	results, _, _, err = c.getValues(v)
+	return
+}
+
+// getValues returns values from Vault alongside the keys which hold structured
+// values (lists or maps) and the lease of the secret, if it has one
+func (c *Client) getValues(v *schemas.Vault) (results map[string]string, structured map[string]bool, lease *schemas.VariableLease, err error) {
 	results = make(map[string]string)
+	structured = make(map[string]bool)
 	if v != nil && v.Path != nil {
 		var secret *api.Secret
 
+		client := c
+		if v.Namespace != nil {
+			if client, err = c.WithNamespace(*v.Namespace); err != nil {
+				return
+			}
+		}
+
 		if v.Method == nil {
 			m := "read"
 			v.Method = &m
 		}
 
+		if v.Mount != nil {
+			return client.getKVv2Values(v)
+		}
+
+		if v.Version != nil {
+			return results, structured, nil, fmt.Errorf("'version' can only be used alongside 'mount' for kv-v2 secrets")
+		}
+
 		switch *v.Method {
 		case "read":
-			secret, err = c.Logical().Read(*v.Path)
+			secret, err = client.Logical().Read(*v.Path)
 		case "write":
 			params := map[string]interface{}{}
 			if v.Params != nil {
@@ -77,34 +124,213 @@ func (c *Client) GetValues(v *schemas.Vault) (results map[string]string, err err
 					params[k] = v
 				}
 			}
-			secret, err = c.Logical().Write(*v.Path, params)
+			secret, err = client.Logical().Write(*v.Path, params)
 		default:
-			return results, fmt.Errorf("unsupported method '%s'", *v.Method)
+			return results, structured, nil, fmt.Errorf("unsupported method '%s'", *v.Method)
 		}
 
 		if err != nil {
-			return results, fmt.Errorf("vault error : %s", err)
+			return results, structured, nil, fmt.Errorf("vault error : %s", err)
 		}
 
 		if secret == nil || len(secret.Data) == 0 {
-			return results, fmt.Errorf("no results/keys returned for secret : %s", *v.Path)
+			return results, structured, nil, fmt.Errorf("no results/keys returned for secret : %s", *v.Path)
 		}
 
 		// kv-v2 backend returns a slightly different response than others
+		data := secret.Data
 		_, hasDataField := secret.Data["data"]
 		_, hasMetaDataField := secret.Data["metadata"]
 		if hasDataField && hasMetaDataField {
-			for k, v := range secret.Data["data"].(map[string]interface{}) {
-				results[k] = v.(string)
-			}
-		} else {
-			for k, v := range secret.Data {
-				results[k] = v.(string)
+			var ok bool
+			if data, ok = secret.Data["data"].(map[string]interface{}); !ok {
+				return results, structured, nil, fmt.Errorf("unexpected kv-v2 data format for secret : %s", *v.Path)
 			}
 		}
 
+		lease = getLease(secret)
+		err = convertValues(data, results, structured)
 		return
 	}
 
-	return results, fmt.Errorf("no path defined for retrieving vault secret")
+	return results, structured, nil, fmt.Errorf("no path defined for retrieving vault secret")
+}
+
+// getKVv2Values reads a (specific version of a) secret from a kv-v2 secret engine
+func (c *Client) getKVv2Values(v *schemas.Vault) (results map[string]string, structured map[string]bool, lease *schemas.VariableLease, err error) {
+	results = make(map[string]string)
+	structured = make(map[string]bool)
+
+	if *v.Method != "read" {
+		return results, structured, nil, fmt.Errorf("only the 'read' method can be used alongside 'mount'")
+	}
+
+	path := fmt.Sprintf("%s/data/%s", strings.Trim(*v.Mount, "/"), strings.TrimLeft(*v.Path, "/"))
+	version := "latest"
+	var params map[string][]string
+	if v.Version != nil {
+		version = strconv.Itoa(*v.Version)
+		params = map[string][]string{"version": {version}}
+	}
+
+	secret, err := c.Logical().ReadWithData(path, params)
+	if err != nil {
+		return results, structured, nil, fmt.Errorf("vault error : %s", err)
+	}
+
+	if secret == nil || len(secret.Data) == 0 {
+		return results, structured, nil, fmt.Errorf("no results/keys returned for secret : %s (version: %s)", path, version)
+	}
+
+	if metadata, ok := secret.Data["metadata"].(map[string]interface{}); ok {
+		if destroyed, _ := metadata["destroyed"].(bool); destroyed {
+			return results, structured, nil, fmt.Errorf("secret %s (version: %s) has been destroyed", path, version)
+		}
+
+		// Versions are scheduled for deletion when delete_version_after is configured, they remain valid until then
+		if deletionTime, _ := metadata["deletion_time"].(string); len(deletionTime) > 0 {
+			deletedAt, err := time.Parse(time.RFC3339Nano, deletionTime)
+			if err != nil {
+				return results, structured, nil, fmt.Errorf("unable to parse the deletion time of secret %s (version: %s) : %s", path, version, err)
+			}
+
+			if !deletedAt.After(time.Now()) {
+				return results, structured, nil, fmt.Errorf("secret %s (version: %s) has been deleted at %s", path, version, deletionTime)
+			}
+		}
+	}
+
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok {
+		return results, structured, nil, fmt.Errorf("no data returned for secret : %s (version: %s)", path, version)
+	}
+
+	err = convertValues(data, results, structured)
+	return
+}
+
+// getLease returns the lease of a dynamic secret, nil if it is not leased
+func getLease(secret *api.Secret) *schemas.VariableLease {
+	if len(secret.LeaseID) == 0 {
+		return nil
+	}
+
+	return &schemas.VariableLease{
+		ID:       secret.LeaseID,
+		Duration: time.Duration(secret.LeaseDuration) * time.Second,
+	}
+}
+
+// convertValues converts the values returned by Vault to strings, keys holding
+// structured values (lists or maps) are flagged in structured
+func convertValues(data map[string]interface{}, results map[string]string, structured map[string]bool) (err error) {
+	for k, value := range data {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			structured[k] = true
+		}
+
+		if results[k], err = jsonpath.String(value); err != nil {
+			return fmt.Errorf("unable to convert the value of key '%s' : %s", k, err)
+		}
+	}
+	return
+}
+
+// getTypedValue returns the typed value of a structured value serialized as JSON
+func getTypedValue(value string) (cty.Value, error) {
+	t, err := ctyjson.ImpliedType([]byte(value))
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal([]byte(value), t)
+}
+
+// WithNamespace returns a copy of the client targeting another namespace
+func (c *Client) WithNamespace(namespace string) (*Client, error) {
+	clone, err := c.Client.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("Error cloning Vault client: %s", err.Error())
+	}
+
+	clone.SetToken(c.Token())
+	clone.SetNamespace(namespace)
+	return &Client{clone}, nil
+}
+
+// GetVariableValues returns the values of a variable from Vault, several values
+// are returned when the variable maps multiple keys
+func (c *Client) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	values, structured, lease, err := c.getValues(v.Vault)
+	if err != nil {
+		return nil, fmt.Errorf("error getting values from vault for variable '%s' : %s", v.Name, err)
+	}
+
+	// We can map several keys in a single API call
+	if (v.Vault.Key == nil && (v.Vault.Keys == nil || len(*v.Vault.Keys) == 0)) ||
+		(v.Vault.Key != nil && v.Vault.Keys != nil && len(*v.Vault.Keys) > 0) {
+		return nil, fmt.Errorf("you either need to set 'key' or 'keys' when using the Vault provider")
+	}
+
+	variablesWithValues, err := v.MapValues(values, v.Vault.Key, v.Vault.Keys, fmt.Sprintf("secret '%s'", *v.Vault.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vv := range variablesWithValues {
+		vv.Lease = lease
+	}
+
+	// Structured values are typed, terraform variables holding them get declared as HCL
+	// unless explicitly configured otherwise
+	keys := map[string]string{}
+	if v.Vault.Key != nil {
+		keys[v.Name] = *v.Vault.Key
+	} else {
+		for key, variableName := range *v.Vault.Keys {
+			keys[variableName] = key
+		}
+	}
+
+	for _, vv := range variablesWithValues {
+		if !structured[keys[vv.Name]] {
+			continue
+		}
+
+		value, err := getTypedValue(vv.Value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert the value of variable '%s' : %s", vv.Name, err)
+		}
+
+		if err = vv.SetTypedValue(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return variablesWithValues, nil
+}
+
+// RevokeLease revokes a lease which has been obtained whilst fetching the values of a variable
+func (c *Client) RevokeLease(v *schemas.Vault, leaseID string) (err error) {
+	client := c
+	if v != nil && v.Namespace != nil {
+		if client, err = c.WithNamespace(*v.Namespace); err != nil {
+			return
+		}
+	}
+
+	if err = client.Sys().Revoke(leaseID); err != nil {
+		return fmt.Errorf("error revoking vault lease '%s' : %s", leaseID, err)
+	}
+	return
+}
+
+// GetVariableNames returns the names of the variables mapped through 'keys'
+func (c *Client) GetVariableNames(v *schemas.Variable) (names []string) {
+	if v.Vault != nil && v.Vault.Keys != nil {
+		for _, variableName := range *v.Vault.Keys {
+			names = append(names, variableName)
+		}
+	}
+	return
 }
@@ -7,10 +7,16 @@
 package vault
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,21 +27,226 @@ func TestGetClient(t *testing.T) {
 	os.Unsetenv("VAULT_ADDR")
 	os.Unsetenv("VAULT_TOKEN")
 
-	_, err := GetClient("", "")
+	_, err := GetClient("", "", "", nil)
 	assert.Equal(t, fmt.Errorf("Vault address is not defined"), err)
 
-	_, err = GetClient("foo", "")
+	_, err = GetClient("foo", "", "", nil)
 	assert.Equal(t, fmt.Errorf("Vault token is not defined (VAULT_TOKEN or ~/.vault-token)"), err)
 
-	_, err = GetClient("foo", "bar")
+	_, err = GetClient("foo", "bar", "", nil)
 	assert.Nil(t, err)
 
 	os.Setenv("VAULT_ADDR", "foo")
 	os.Setenv("VAULT_TOKEN", "bar")
-	_, err = GetClient("", "")
+	_, err = GetClient("", "", "", nil)
 	assert.Nil(t, err)
 }
 
+func createTestVaultServerWithResponse(response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(response))
+	}))
+}
+
+func TestGetValuesTyped(t *testing.T) {
+	server := createTestVaultServerWithResponse(`{"data":{"name":"foo","port":5432,"enabled":true,"empty":null,"hosts":["a","b"],"tags":{"env":"prod"}}}`)
+	defer server.Close()
+
+	c, err := GetClient(server.URL, "_", "", nil)
+	assert.NoError(t, err)
+
+	values, err := c.GetValues(&schemas.Vault{Path: pointy.String("database/creds/foo")})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"name":    "foo",
+		"port":    "5432",
+		"enabled": "true",
+		"empty":   "",
+		"hosts":   `["a","b"]`,
+		"tags":    `{"env":"prod"}`,
+	}, values)
+}
+
+func TestGetVariableValuesStructured(t *testing.T) {
+	server := createTestVaultServerWithResponse(`{"data":{"data":{"name":"foo","hosts":["a","b"]},"metadata":{"version":1}}}`)
+	defer server.Close()
+
+	c, err := GetClient(server.URL, "_", "", nil)
+	assert.NoError(t, err)
+
+	v := &schemas.Variable{
+		Name: "_",
+		Kind: schemas.VariableKindTerraform,
+		Vault: &schemas.Vault{
+			Path: pointy.String("secret/data/foo"),
+			Keys: &map[string]string{
+				"name":  "name",
+				"hosts": "hosts",
+			},
+		},
+	}
+
+	// Structured values get marked as HCL on terraform variables
+	values, err := c.GetVariableValues(v)
+	assert.NoError(t, err)
+	assert.Len(t, values, 2)
+	for _, vv := range values {
+		switch vv.Name {
+		case "name":
+			assert.Equal(t, "foo", vv.Value)
+			assert.Nil(t, vv.HCL)
+		case "hosts":
+			assert.Equal(t, `["a", "b"]`, vv.Value)
+			assert.Equal(t, pointy.Bool(true), vv.HCL)
+			assert.True(t, vv.HasStructuredValue())
+		}
+	}
+
+	// Unless explicitly configured, they are then serialized as JSON
+	v.HCL = pointy.Bool(false)
+	values, err = c.GetVariableValues(v)
+	assert.NoError(t, err)
+	for _, vv := range values {
+		assert.Equal(t, pointy.Bool(false), vv.HCL)
+		if vv.Name == "hosts" {
+			assert.Equal(t, `["a","b"]`, vv.Value)
+		}
+	}
+
+	// Environment variables cannot be HCL, structured values are serialized as JSON
+	v.HCL = nil
+	v.Kind = schemas.VariableKindEnvironment
+	values, err = c.GetVariableValues(v)
+	assert.NoError(t, err)
+	for _, vv := range values {
+		assert.Nil(t, vv.HCL)
+		if vv.Name == "hosts" {
+			assert.Equal(t, `["a","b"]`, vv.Value)
+		}
+	}
+}
+
+func TestGetValuesKVv2(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/data/foo" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch r.URL.Query().Get("version") {
+		case "":
+			_, _ = w.Write([]byte(`{"data":{"data":{"secret":"latest"},"metadata":{"version":3,"deletion_time":"","destroyed":false}}}`))
+		case "1":
+			_, _ = w.Write([]byte(`{"data":{"data":{"secret":"v1"},"metadata":{"version":1,"deletion_time":"","destroyed":false}}}`))
+		case "5":
+			_, _ = w.Write([]byte(`{"data":{"data":{"secret":"v5"},"metadata":{"version":5,"deletion_time":"2100-01-01T00:00:00.123456Z","destroyed":false}}}`))
+		case "6":
+			_, _ = w.Write([]byte(`{"data":{"data":{"secret":"v6"},"metadata":{"version":6,"deletion_time":"foo","destroyed":false}}}`))
+		case "2":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"data":{"data":null,"metadata":{"version":2,"deletion_time":"2022-03-01T10:00:00Z","destroyed":false}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"data":{"data":null,"metadata":{"version":4,"deletion_time":"","destroyed":true}}}`))
+		}
+	}))
+	defer server.Close()
+
+	c, err := GetClient(server.URL, "_", "", nil)
+	assert.NoError(t, err)
+
+	// Latest version
+	values, err := c.GetValues(&schemas.Vault{Mount: pointy.String("/kv/"), Path: pointy.String("foo")})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"secret": "latest"}, values)
+
+	// Pinned version
+	values, err = c.GetValues(&schemas.Vault{Mount: pointy.String("kv"), Path: pointy.String("foo"), Version: pointy.Int(1)})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"secret": "v1"}, values)
+
+	// Version scheduled for deletion (delete_version_after)
+	values, err = c.GetValues(&schemas.Vault{Mount: pointy.String("kv"), Path: pointy.String("foo"), Version: pointy.Int(5)})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"secret": "v5"}, values)
+
+	_, err = c.GetValues(&schemas.Vault{Mount: pointy.String("kv"), Path: pointy.String("foo"), Version: pointy.Int(6)})
+	assert.EqualError(t, err, "unable to parse the deletion time of secret kv/data/foo (version: 6) : parsing time \"foo\" as \"2006-01-02T15:04:05.999999999Z07:00\": cannot parse \"foo\" as \"2006\"")
+
+	// Deleted version
+	_, err = c.GetValues(&schemas.Vault{Mount: pointy.String("kv"), Path: pointy.String("foo"), Version: pointy.Int(2)})
+	assert.EqualError(t, err, "secret kv/data/foo (version: 2) has been deleted at 2022-03-01T10:00:00Z")
+
+	// Destroyed version
+	_, err = c.GetValues(&schemas.Vault{Mount: pointy.String("kv"), Path: pointy.String("foo"), Version: pointy.Int(4)})
+	assert.EqualError(t, err, "secret kv/data/foo (version: 4) has been destroyed")
+
+	// Unexistent secret
+	_, err = c.GetValues(&schemas.Vault{Mount: pointy.String("kv"), Path: pointy.String("bar")})
+	assert.EqualError(t, err, "no results/keys returned for secret : kv/data/bar (version: latest)")
+
+	// Invalid configurations
+	_, err = c.GetValues(&schemas.Vault{Path: pointy.String("foo"), Version: pointy.Int(1)})
+	assert.EqualError(t, err, "'version' can only be used alongside 'mount' for kv-v2 secrets")
+
+	_, err = c.GetValues(&schemas.Vault{Mount: pointy.String("kv"), Path: pointy.String("foo"), Method: pointy.String("write")})
+	assert.EqualError(t, err, "only the 'read' method can be used alongside 'mount'")
+}
+
+func TestLeases(t *testing.T) {
+	revokedLeases := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/database/creds/foo":
+			_, _ = w.Write([]byte(`{"lease_id":"database/creds/foo/abc","lease_duration":3600,"renewable":true,"data":{"username":"foo","password":"bar"}}`))
+		case "/v1/secret/foo":
+			_, _ = w.Write([]byte(`{"lease_duration":2764800,"data":{"username":"foo"}}`))
+		case "/v1/sys/leases/revoke":
+			body := struct {
+				LeaseID string `json:"lease_id"`
+			}{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			revokedLeases = append(revokedLeases, body.LeaseID)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c, err := GetClient(server.URL, "_", "", nil)
+	assert.NoError(t, err)
+
+	v := &schemas.Variable{
+		Name: "_",
+		Vault: &schemas.Vault{
+			Path: pointy.String("database/creds/foo"),
+			Keys: &map[string]string{
+				"username": "user",
+				"password": "pass",
+			},
+		},
+	}
+
+	// Leases are referenced on every value of a dynamic secret
+	values, err := c.GetVariableValues(v)
+	assert.NoError(t, err)
+	assert.Len(t, values, 2)
+	for _, vv := range values {
+		assert.Equal(t, &schemas.VariableLease{ID: "database/creds/foo/abc", Duration: time.Hour}, vv.Lease)
+	}
+
+	// Static secrets do not have any lease
+	v.Vault.Path = pointy.String("secret/foo")
+	v.Vault.Keys = &map[string]string{"username": "user"}
+	values, err = c.GetVariableValues(v)
+	assert.NoError(t, err)
+	assert.Nil(t, values[0].Lease)
+
+	assert.NoError(t, c.RevokeLease(v.Vault, "database/creds/foo/abc"))
+	assert.Equal(t, []string{"database/creds/foo/abc"}, revokedLeases)
+}
+
 // func TestGetValues(t *testing.T) {
 // 	ln, client := createTestVault(t)
 // 	defer ln.Close()
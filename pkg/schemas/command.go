@@ -0,0 +1,12 @@
+package schemas
+
+// Command is a provider type
+type Command struct {
+	Command    string             `hcl:"command"`
+	Args       *[]string          `hcl:"args"`
+	WorkingDir *string            `hcl:"working-dir"`
+	Env        *map[string]string `hcl:"env"`
+	Timeout    *string            `hcl:"timeout"`
+	Trim       *bool              `hcl:"trim"`
+	JSONPath   *string            `hcl:"json-path"`
+}
@@ -1,6 +1,9 @@
 package schemas
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -9,10 +12,11 @@ import (
 // Config handles all components that can be defined in
 // a tfcw config file
 type Config struct {
-	TFC                  *TFC      `hcl:"tfc,block"`
-	Defaults             *Defaults `hcl:"defaults,block"`
-	TerraformVariables   Variables `hcl:"tfvar,block"`
-	EnvironmentVariables Variables `hcl:"envvar,block"`
+	TFC                  *TFC          `hcl:"tfc,block"`
+	Defaults             *Defaults     `hcl:"defaults,block"`
+	State                *StateStorage `hcl:"state,block"`
+	TerraformVariables   Variables     `hcl:"tfvar,block"`
+	EnvironmentVariables Variables     `hcl:"envvar,block"`
 
 	Runtime Runtime
 }
@@ -29,6 +33,21 @@ type Runtime struct {
 	}
 }
 
+// GetSkipUnchangedSensitiveVariables returns whether the writes of sensitive values matching their fingerprints
+// should be skipped, defaults to false as their in-place edits from the TFC UI cannot be detected. It requires
+// a fingerprints key to be configured
+func (cfg *Config) GetSkipUnchangedSensitiveVariables() (bool, error) {
+	if cfg.TFC == nil || cfg.TFC.SkipUnchangedSensitiveVariables == nil || !*cfg.TFC.SkipUnchangedSensitiveVariables {
+		return false, nil
+	}
+
+	if len(cfg.State.GetFingerprintsKey()) == 0 {
+		return false, fmt.Errorf("a fingerprints-key has to be defined in the state block in order to skip unchanged sensitive variables")
+	}
+
+	return true, nil
+}
+
 // GetVariables returns a Variables containing the configured variables
 func (cfg *Config) GetVariables() (variables Variables) {
 	for _, variable := range cfg.TerraformVariables {
@@ -44,20 +63,65 @@ func (cfg *Config) GetVariables() (variables Variables) {
 	return
 }
 
-// GetVariableTTL returns the TTL of a variable
-func (cfg *Config) GetVariableTTL(v *Variable) (ttl time.Duration, err error) {
+// GetVariableHCL returns whether the variable has to be declared as HCL, using the defaults when it is not
+// explicitly configured. Nil is returned when neither of them define it, leaving the decision to the type of
+// its value
+func (cfg *Config) GetVariableHCL(v *Variable) *bool {
+	hcl := v.HCL
+	if hcl == nil && cfg.Defaults != nil && cfg.Defaults.Variable != nil {
+		hcl = cfg.Defaults.Variable.HCL
+	}
+
+	if hcl == nil {
+		return nil
+	}
+
+	value := *hcl
+	return &value
+}
+
+// GetVariableTTL returns the TTL of a variable, the lease of its value is used when the TTL
+// is derived from its duration ("lease" or "lease*<factor>")
+func (cfg *Config) GetVariableTTL(v *Variable, lease *VariableLease) (ttl time.Duration, err error) {
 	if v.TTL != nil {
-		return time.ParseDuration(*v.TTL)
+		return parseTTL(*v.TTL, lease)
 	}
 
 	if cfg.Defaults != nil && cfg.Defaults.Variable != nil && cfg.Defaults.Variable.TTL != nil {
-		return time.ParseDuration(*cfg.Defaults.Variable.TTL)
+		return parseTTL(*cfg.Defaults.Variable.TTL, lease)
 	}
 	return time.Duration(0), nil
 }
 
-// ComputeNewVariableExpirations ...
-func (cfg *Config) ComputeNewVariableExpirations(updatedVariables Variables, existingVariableExpirations VariableExpirations) (variableExpirations VariableExpirations, hasChanges bool, err error) {
+// parseTTL parses either a duration or an expression based on the duration of a lease,
+// values which are not leased get a TTL of 0 and are therefore updated on every run
+func parseTTL(ttl string, lease *VariableLease) (time.Duration, error) {
+	if !strings.HasPrefix(ttl, LeaseTTL) {
+		return time.ParseDuration(ttl)
+	}
+
+	factor := 1.0
+	if expr := strings.TrimSpace(strings.TrimPrefix(ttl, LeaseTTL)); len(expr) > 0 {
+		var err error
+		if !strings.HasPrefix(expr, "*") {
+			return 0, fmt.Errorf("invalid ttl '%s', expected 'lease' or 'lease*<factor>'", ttl)
+		}
+
+		if factor, err = strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(expr, "*")), 64); err != nil || factor <= 0 || factor > 1 {
+			return 0, fmt.Errorf("invalid ttl '%s', the factor must be a number within ]0,1]", ttl)
+		}
+	}
+
+	if lease == nil {
+		return 0, nil
+	}
+
+	return time.Duration(float64(lease.Duration) * factor), nil
+}
+
+// ComputeNewVariableExpirations returns the expirations of the variables once the updated ones have been
+// rendered, leases are kept even for variables without TTL in order to be able to revoke them later on
+func (cfg *Config) ComputeNewVariableExpirations(updatedVariables Variables, existingVariableExpirations VariableExpirations, leases VariableLeases) (variableExpirations VariableExpirations, hasChanges bool, err error) {
 	if len(existingVariableExpirations) > 0 {
 		variableExpirations = existingVariableExpirations
 	} else {
@@ -73,14 +137,16 @@ func (cfg *Config) ComputeNewVariableExpirations(updatedVariables Variables, exi
 			variableExpirations[v.Kind] = map[string]*VariableExpiration{}
 		}
 
+		lease := leases.Get(v)
+
 		var ttl time.Duration
-		ttl, err = cfg.GetVariableTTL(v)
+		ttl, err = cfg.GetVariableTTL(v, lease)
 		if err != nil {
 			return
 		}
 
-		// If there is no TTL defined, we omit this variable from the expirations list
-		if ttl == 0 {
+		// If there is no TTL nor lease defined, we omit this variable from the expirations list
+		if ttl == 0 && lease == nil {
 			if _, ok := variableExpirations[v.Kind][v.Name]; ok {
 				delete(variableExpirations[v.Kind], v.Name)
 			}
@@ -90,6 +156,7 @@ func (cfg *Config) ComputeNewVariableExpirations(updatedVariables Variables, exi
 		variableExpirations[v.Kind][v.Name] = &VariableExpiration{
 			TTL:      ttl,
 			ExpireAt: time.Now().Add(ttl),
+			Lease:    lease,
 		}
 	}
 
@@ -107,15 +174,22 @@ func (cfg *Config) ComputeNewVariableExpirations(updatedVariables Variables, exi
 // and the existing variables
 func (cfg *Config) GetVariablesToUpdate(variableExpirations VariableExpirations) (variables Variables, err error) {
 	for _, v := range cfg.GetVariables() {
+		// TTLs derived from leases are computed using the lease of the value currently rendered
+		var lease *VariableLease
+		variableExpiration, hasExpiration := variableExpirations[v.Kind][v.Name]
+		if hasExpiration {
+			lease = variableExpiration.Lease
+		}
+
 		var ttl time.Duration
-		ttl, err = cfg.GetVariableTTL(v)
+		ttl, err = cfg.GetVariableTTL(v, lease)
 		if err != nil {
 			return
 		}
 
 		if ttl > 0 {
 			// Check if there is a TTL flag set for this variable
-			if variableExpiration, ok := variableExpirations[v.Kind][v.Name]; ok {
+			if hasExpiration {
 				// If the TTL hasn't changed and the expiration date is still in the future we do not update it
 				if ttl == variableExpiration.TTL && variableExpiration.ExpireAt.After(time.Now()) {
 					log.Debugf("variable %s (%s) is still valid for %s (ttl: %s), not updating", v.Name, v.Kind, variableExpiration.ExpireAt.Sub(time.Now()).String(), variableExpiration.TTL.String())
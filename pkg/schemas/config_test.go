@@ -47,18 +47,60 @@ func TestConfigGetVariables(t *testing.T) {
 	}, *(variables[1]))
 }
 
+func TestConfigGetVariableHCL(t *testing.T) {
+	cfg := &Config{
+		TerraformVariables: Variables{
+			&Variable{Name: "foo"},
+			&Variable{Name: "bar", HCL: pointy.Bool(true)},
+		},
+	}
+
+	variables := cfg.GetVariables()
+	assert.Nil(t, cfg.GetVariableHCL(variables[0]))
+	assert.Equal(t, pointy.Bool(true), cfg.GetVariableHCL(variables[1]))
+
+	cfg.Defaults = &Defaults{
+		Variable: &VariableDefaults{
+			HCL: pointy.Bool(false),
+		},
+	}
+
+	assert.Equal(t, pointy.Bool(false), cfg.GetVariableHCL(variables[0]))
+	assert.Equal(t, pointy.Bool(true), cfg.GetVariableHCL(variables[1]))
+
+	// The config is left untouched and the default is not shared
+	assert.Nil(t, cfg.GetVariables()[0].HCL)
+	assert.NotSame(t, cfg.Defaults.Variable.HCL, cfg.GetVariableHCL(variables[0]))
+}
+
+func TestConfigGetSkipUnchangedSensitiveVariables(t *testing.T) {
+	cfg := &Config{}
+	skip, err := cfg.GetSkipUnchangedSensitiveVariables()
+	assert.NoError(t, err)
+	assert.False(t, skip)
+
+	cfg.TFC = &TFC{SkipUnchangedSensitiveVariables: pointy.Bool(true)}
+	_, err = cfg.GetSkipUnchangedSensitiveVariables()
+	assert.EqualError(t, err, "a fingerprints-key has to be defined in the state block in order to skip unchanged sensitive variables")
+
+	cfg.State = &StateStorage{FingerprintsKey: pointy.String("foo")}
+	skip, err = cfg.GetSkipUnchangedSensitiveVariables()
+	assert.NoError(t, err)
+	assert.True(t, skip)
+}
+
 func TestConfigGetVariableTTL(t *testing.T) {
 	// Defining the TTL in the Variable
-	ttl, err := testConfig.GetVariableTTL(&Variable{TTL: pointy.String("30m")})
+	ttl, err := testConfig.GetVariableTTL(&Variable{TTL: pointy.String("30m")}, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, thirtyMinuteDuration, ttl)
 
 	// With an incorrect value in the variable definition
-	_, err = testConfig.GetVariableTTL(&Variable{TTL: pointy.String("foo")})
+	_, err = testConfig.GetVariableTTL(&Variable{TTL: pointy.String("foo")}, nil)
 	assert.Error(t, err)
 
 	// Using the default configuration value
-	ttl, err = testConfig.GetVariableTTL(&Variable{})
+	ttl, err = testConfig.GetVariableTTL(&Variable{}, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, fifteenMinuteDuration, ttl)
 
@@ -71,14 +113,52 @@ func TestConfigGetVariableTTL(t *testing.T) {
 		},
 	}
 
-	_, err = incorrectDefaultConfig.GetVariableTTL(&Variable{})
+	_, err = incorrectDefaultConfig.GetVariableTTL(&Variable{}, nil)
 	assert.Error(t, err)
 
 	// Without any configuration
 	emptyConfig := &Config{}
-	ttl, err = emptyConfig.GetVariableTTL(&Variable{})
+	ttl, err = emptyConfig.GetVariableTTL(&Variable{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+}
+
+func TestConfigGetVariableTTLFromLease(t *testing.T) {
+	lease := &VariableLease{ID: "aws/creds/foo/bar", Duration: time.Hour}
+
+	// Using the duration of the lease
+	ttl, err := testConfig.GetVariableTTL(&Variable{TTL: pointy.String("lease")}, lease)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, ttl)
+
+	// Using a fraction of it
+	ttl, err = testConfig.GetVariableTTL(&Variable{TTL: pointy.String("lease * 0.8")}, lease)
+	assert.NoError(t, err)
+	assert.Equal(t, 48*time.Minute, ttl)
+
+	// From the defaults
+	leaseDefaultConfig := &Config{
+		Defaults: &Defaults{
+			Variable: &VariableDefaults{
+				TTL: pointy.String("lease*0.5"),
+			},
+		},
+	}
+
+	ttl, err = leaseDefaultConfig.GetVariableTTL(&Variable{}, lease)
+	assert.NoError(t, err)
+	assert.Equal(t, thirtyMinuteDuration, ttl)
+
+	// Without any lease
+	ttl, err = testConfig.GetVariableTTL(&Variable{TTL: pointy.String("lease")}, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, time.Duration(0), ttl)
+
+	// With incorrect expressions
+	for _, invalidTTL := range []string{"lease/2", "lease*foo", "lease*0", "lease*1.5", "leases"} {
+		_, err = testConfig.GetVariableTTL(&Variable{TTL: pointy.String(invalidTTL)}, lease)
+		assert.Error(t, err, invalidTTL)
+	}
 }
 
 func TestComputeNewVariableExpirations(t *testing.T) {
@@ -90,7 +170,7 @@ func TestComputeNewVariableExpirations(t *testing.T) {
 		},
 	}
 
-	variableExpirations, hasChanges, err := testConfig.ComputeNewVariableExpirations(updatedVariables, existingVariableExpirations)
+	variableExpirations, hasChanges, err := testConfig.ComputeNewVariableExpirations(updatedVariables, existingVariableExpirations, nil)
 	assert.NoError(t, err)
 	assert.True(t, hasChanges)
 	assert.NotNil(t, variableExpirations)
@@ -108,7 +188,7 @@ func TestComputeNewVariableExpirations(t *testing.T) {
 		ExpireAt: time.Now().Add(fifteenMinuteDuration),
 	}
 
-	_, hasChanges, err = testConfig.ComputeNewVariableExpirations(Variables{}, existingVariableExpirations)
+	_, hasChanges, err = testConfig.ComputeNewVariableExpirations(Variables{}, existingVariableExpirations, nil)
 	assert.NoError(t, err)
 	assert.False(t, hasChanges)
 
@@ -121,12 +201,22 @@ func TestComputeNewVariableExpirations(t *testing.T) {
 		},
 	}
 
-	variableExpirations, hasChanges, err = emptyConfig.ComputeNewVariableExpirations(updatedVariables, existingVariableExpirations)
+	variableExpirations, hasChanges, err = emptyConfig.ComputeNewVariableExpirations(updatedVariables, existingVariableExpirations, nil)
 	assert.NoError(t, err)
 	assert.True(t, hasChanges)
 	assert.Len(t, variableExpirations, 0)
 	fmt.Println(variableExpirations)
 
+	// No TTL defined on an updatedVariable holding a lease
+	lease := &VariableLease{ID: "aws/creds/foo/bar", Duration: thirtyMinuteDuration}
+	leases := VariableLeases{}
+	leases.Set(updatedVariables[0], lease)
+
+	variableExpirations, _, err = emptyConfig.ComputeNewVariableExpirations(updatedVariables, existingVariableExpirations, leases)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), variableExpirations[VariableKindTerraform]["foo"].TTL)
+	assert.Equal(t, lease, variableExpirations[VariableKindTerraform]["foo"].Lease)
+
 	// Invalid TTL of an updated variable
 	updatedVariables = Variables{
 		&Variable{
@@ -134,7 +224,7 @@ func TestComputeNewVariableExpirations(t *testing.T) {
 			TTL:  pointy.String("bar"),
 		},
 	}
-	_, _, err = testConfig.ComputeNewVariableExpirations(updatedVariables, existingVariableExpirations)
+	_, _, err = testConfig.ComputeNewVariableExpirations(updatedVariables, existingVariableExpirations, nil)
 	assert.Error(t, err)
 }
 
@@ -157,6 +247,30 @@ func TestConfigGetVariablesToUpdate(t *testing.T) {
 	assert.Len(t, variables, 1)
 	assert.Equal(t, "bar", variables[0].Name)
 
+	// TTL derived from the lease of the value currently rendered
+	leaseTTLConfig := &Config{
+		TerraformVariables: Variables{
+			&Variable{
+				Name: "foo",
+				TTL:  pointy.String("lease*0.5"),
+			},
+		},
+	}
+	variableExpirations[VariableKindTerraform]["foo"] = &VariableExpiration{
+		TTL:      fifteenMinuteDuration,
+		ExpireAt: time.Now().Add(fifteenMinuteDuration),
+		Lease:    &VariableLease{ID: "aws/creds/foo/bar", Duration: thirtyMinuteDuration},
+	}
+	variables, err = leaseTTLConfig.GetVariablesToUpdate(variableExpirations)
+	assert.NoError(t, err)
+	assert.Len(t, variables, 0)
+
+	// Unless the TTL expression got updated
+	leaseTTLConfig.TerraformVariables[0].TTL = pointy.String("lease")
+	variables, err = leaseTTLConfig.GetVariablesToUpdate(variableExpirations)
+	assert.NoError(t, err)
+	assert.Len(t, variables, 1)
+
 	// Invalid variable TTL
 	invalidVariableTTLConfig := &Config{
 		TerraformVariables: Variables{
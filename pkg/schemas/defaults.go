@@ -9,7 +9,9 @@ type Defaults struct {
 
 // VariableDefaults can handle default values for variables
 type VariableDefaults struct {
-	Sensitive *bool   `hcl:"sensitive"`
-	HCL       *bool   `hcl:"hcl"`
-	TTL       *string `hcl:"ttl"`
+	Sensitive   *bool   `hcl:"sensitive"`
+	HCL         *bool   `hcl:"hcl"`
+	TTL         *string `hcl:"ttl"`
+	Description *string `hcl:"description"`
+	Category    *string `hcl:"category"`
 }
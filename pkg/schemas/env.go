@@ -2,5 +2,8 @@ package schemas
 
 // Env is a provider type
 type Env struct {
-	Variable string `hcl:"variable"`
+	Variable   string  `hcl:"variable"`
+	Default    *string `hcl:"default"`
+	Required   *bool   `hcl:"required"`
+	AllowEmpty *bool   `hcl:"allow-empty"`
 }
@@ -0,0 +1,29 @@
+package schemas
+
+// File is a provider type
+type File struct {
+	Path   string          `hcl:"path"`
+	Format *FileFormatType `hcl:"format"`
+	Key    *string         `hcl:"key"`
+	Base64 *bool           `hcl:"base64"`
+}
+
+// FileFormatType represents the format of a file
+type FileFormatType string
+
+const (
+	// FileFormatTypeRaw refers to a file which content is used as is
+	FileFormatTypeRaw FileFormatType = "raw"
+
+	// FileFormatTypeJSON refers to a JSON file
+	FileFormatTypeJSON FileFormatType = "json"
+
+	// FileFormatTypeYAML refers to a YAML file
+	FileFormatTypeYAML FileFormatType = "yaml"
+
+	// FileFormatTypeDotenv refers to a dotenv file
+	FileFormatTypeDotenv FileFormatType = "dotenv"
+
+	// FileFormatTypeHCL refers to an HCL file
+	FileFormatTypeHCL FileFormatType = "hcl"
+)
@@ -0,0 +1,11 @@
+package schemas
+
+import (
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Literal is a provider type, declared as a 'value' block. Its value can either be
+// a string or any other HCL type (number, bool, list, map or object)
+type Literal struct {
+	Value cty.Value `hcl:"value"`
+}
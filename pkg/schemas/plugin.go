@@ -0,0 +1,11 @@
+package schemas
+
+// Plugin is a provider type
+type Plugin struct {
+	Command string             `hcl:"command"`
+	Args    *[]string          `hcl:"args"`
+	Config  *map[string]string `hcl:"config"`
+	Timeout *string            `hcl:"timeout"`
+	Key     *string            `hcl:"key"`
+	Keys    *map[string]string `hcl:"keys"`
+}
@@ -4,6 +4,7 @@ package schemas
 type S5 struct {
 	CipherEngineType  *S5CipherEngineType  `hcl:"engine"`
 	CipherEngineAES   *S5CipherEngineAES   `hcl:"aes,block"`
+	CipherEngineAge   *S5CipherEngineAge   `hcl:"age,block"`
 	CipherEngineAWS   *S5CipherEngineAWS   `hcl:"aws,block"`
 	CipherEngineGCP   *S5CipherEngineGCP   `hcl:"gcp,block"`
 	CipherEnginePGP   *S5CipherEnginePGP   `hcl:"pgp,block"`
@@ -18,6 +19,9 @@ const (
 	// S5CipherEngineTypeAES refers to an 'aes' s5 cipher engine type
 	S5CipherEngineTypeAES S5CipherEngineType = "aes"
 
+	// S5CipherEngineTypeAge refers to an 'age' s5 cipher engine type
+	S5CipherEngineTypeAge S5CipherEngineType = "age"
+
 	// S5CipherEngineTypeAWS refers to an 'aws' s5 cipher engine type
 	S5CipherEngineTypeAWS S5CipherEngineType = "aws"
 
@@ -36,6 +40,12 @@ type S5CipherEngineAES struct {
 	Key *string `hcl:"key"`
 }
 
+// S5CipherEngineAge handles necessary configuration for an 'age' s5 cipher engine
+type S5CipherEngineAge struct {
+	IdentitiesPath *string `hcl:"identities-path"`
+	RecipientsPath *string `hcl:"recipients-path"`
+}
+
 // S5CipherEngineAWS handles necessary configuration for an 'aws' s5 cipher engine
 type S5CipherEngineAWS struct {
 	KmsKeyArn *string `hcl:"kms-key-arn"`
@@ -0,0 +1,9 @@
+package schemas
+
+// SOPS is a provider type
+type SOPS struct {
+	Path   string             `hcl:"path"`
+	Format *FileFormatType    `hcl:"format"`
+	Key    *string            `hcl:"key"`
+	Keys   *map[string]string `hcl:"keys"`
+}
@@ -0,0 +1,183 @@
+package schemas
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// State holds what TFCW keeps track of between the renderings of a workspace
+type State struct {
+	WorkspaceID          string                `json:"workspace_id,omitempty"`
+	VariableExpirations  VariableExpirations   `json:"variable_expirations,omitempty"`
+	ManagedVariables     ManagedVariables      `json:"managed_variables,omitempty"`
+	VariableFingerprints *VariableFingerprints `json:"variable_fingerprints,omitempty"`
+}
+
+// IsEmpty returns whether the state does not hold anything
+func (s *State) IsEmpty() bool {
+	return s == nil || (len(s.VariableExpirations) == 0 && len(s.ManagedVariables) == 0 && s.VariableFingerprints.IsEmpty())
+}
+
+// ForgetVariable removes what is known about a variable which has been deleted from the workspace,
+// its expiration is kept until the lease of its value gets revoked
+func (s *State) ForgetVariable(kind VariableKind, name string) {
+	s.ManagedVariables.Remove(kind, name)
+	s.VariableFingerprints.Remove(kind, name)
+}
+
+// VariableFingerprints holds keyed hashes of the values rendered onto TFC, kept alongside the expirations.
+// They allow TFCW to tell whether a value has changed without having to store it, sensitive values not being
+// readable from the TFC API
+type VariableFingerprints struct {
+	Values map[VariableKind]map[string]*VariableFingerprint `json:"values,omitempty"`
+}
+
+// VariableFingerprint references the fingerprint of the value of a variable, alongside the ID of the
+// variable on TFC in order to detect variables which have been recreated outside of TFCW. The hash is
+// empty for values which have not been fingerprinted
+type VariableFingerprint struct {
+	Hash string `json:"hash,omitempty"`
+	ID   string `json:"id"`
+}
+
+// ComputeVariableFingerprint returns the fingerprint of the value of a variable, a HMAC-SHA256 of its kind,
+// name and value keyed with a secret which is never stored alongside the fingerprints
+func ComputeVariableFingerprint(key string, kind VariableKind, name, value string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	_, _ = fmt.Fprintf(h, "%s\x00%s\x00%s", kind, name, value)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Set references the fingerprint of the value of a variable
+func (f *VariableFingerprints) Set(kind VariableKind, name string, fingerprint *VariableFingerprint) {
+	if f.Values == nil {
+		f.Values = map[VariableKind]map[string]*VariableFingerprint{}
+	}
+
+	if _, ok := f.Values[kind]; !ok {
+		f.Values[kind] = map[string]*VariableFingerprint{}
+	}
+	f.Values[kind][name] = fingerprint
+}
+
+// Get returns the fingerprint of the value of a variable, nil if it has not been fingerprinted
+func (f *VariableFingerprints) Get(kind VariableKind, name string) *VariableFingerprint {
+	if f == nil {
+		return nil
+	}
+	return f.Values[kind][name]
+}
+
+// Remove dereferences the fingerprint of the value of a variable
+func (f *VariableFingerprints) Remove(kind VariableKind, name string) {
+	if f == nil {
+		return
+	}
+
+	delete(f.Values[kind], name)
+	if len(f.Values[kind]) == 0 {
+		delete(f.Values, kind)
+	}
+}
+
+// IsEmpty returns whether no value has been fingerprinted
+func (f *VariableFingerprints) IsEmpty() bool {
+	return f == nil || len(f.Values) == 0
+}
+
+// StateBackend represents where the state of TFCW is stored
+type StateBackend string
+
+const (
+	// StateBackendWorkspace stores the state within environment variables of the workspace
+	StateBackendWorkspace StateBackend = "workspace"
+
+	// StateBackendFile stores the state within a local JSON file
+	StateBackendFile StateBackend = "file"
+
+	// StateBackendVault stores the state within a Vault KV secret
+	StateBackendVault StateBackend = "vault"
+
+	// StateBackendS3 stores the state within an S3 (compatible) bucket
+	StateBackendS3 StateBackend = "s3"
+)
+
+const (
+	// StateLocationOrganizationPlaceholder gets replaced by the name of the organization within
+	// the path of the file, the path of the Vault secret or the key of the S3 object holding the state
+	StateLocationOrganizationPlaceholder = "{organization}"
+
+	// StateLocationWorkspacePlaceholder gets replaced by the name of the workspace within
+	// the path of the file, the path of the Vault secret or the key of the S3 object holding the state
+	StateLocationWorkspacePlaceholder = "{workspace}"
+
+	// DefaultStateFilePath is the path of the file used by the 'file' state backend when none is configured
+	DefaultStateFilePath = ".tfcw/" + StateLocationOrganizationPlaceholder + "/" + StateLocationWorkspacePlaceholder + ".state.json"
+)
+
+// StateStorage configures where the state of TFCW is stored
+type StateStorage struct {
+	Backend         *StateBackend `hcl:"backend"`
+	FingerprintsKey *string       `hcl:"fingerprints-key"`
+	File            *StateFile    `hcl:"file,block"`
+	Vault           *StateVault   `hcl:"vault,block"`
+	S3              *StateS3      `hcl:"s3,block"`
+}
+
+// StateFile configures the 'file' state backend
+type StateFile struct {
+	Path *string `hcl:"path"`
+}
+
+// StateVault configures the 'vault' state backend
+type StateVault struct {
+	Address   *string    `hcl:"address"`
+	Token     *string    `hcl:"token"`
+	Namespace *string    `hcl:"namespace"`
+	Auth      *VaultAuth `hcl:"auth,block"`
+	Mount     *string    `hcl:"mount"`
+	Path      string     `hcl:"path"`
+}
+
+// StateS3 configures the 's3' state backend
+type StateS3 struct {
+	Bucket         string  `hcl:"bucket"`
+	Key            string  `hcl:"key"`
+	Region         *string `hcl:"region"`
+	Endpoint       *string `hcl:"endpoint"`
+	ForcePathStyle *bool   `hcl:"force-path-style"`
+}
+
+// GetBackend returns the configured state backend, defaults to StateBackendWorkspace
+func (s *StateStorage) GetBackend() (StateBackend, error) {
+	if s == nil || s.Backend == nil {
+		return StateBackendWorkspace, nil
+	}
+
+	switch *s.Backend {
+	case StateBackendWorkspace, StateBackendFile, StateBackendVault, StateBackendS3:
+		return *s.Backend, nil
+	}
+
+	return "", fmt.Errorf("unsupported state backend '%s', expected one of: %s, %s, %s, %s", *s.Backend, StateBackendWorkspace, StateBackendFile, StateBackendVault, StateBackendS3)
+}
+
+// GetFingerprintsKey returns the key of the fingerprints of the values, empty if not configured
+func (s *StateStorage) GetFingerprintsKey() string {
+	if s == nil || s.FingerprintsKey == nil {
+		return ""
+	}
+	return *s.FingerprintsKey
+}
+
+// GetVault returns the Vault configuration used to get a client for the 'vault' state backend
+func (s *StateVault) GetVault() *Vault {
+	return &Vault{
+		Address:   s.Address,
+		Token:     s.Token,
+		Namespace: s.Namespace,
+		Auth:      s.Auth,
+	}
+}
@@ -0,0 +1,75 @@
+package schemas
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateStorageGetBackend(t *testing.T) {
+	var s *StateStorage
+	backend, err := s.GetBackend()
+	assert.NoError(t, err)
+	assert.Equal(t, StateBackendWorkspace, backend)
+
+	s3 := StateBackendS3
+	s = &StateStorage{Backend: &s3}
+	backend, err = s.GetBackend()
+	assert.NoError(t, err)
+	assert.Equal(t, StateBackendS3, backend)
+
+	invalid := StateBackend("foo")
+	s.Backend = &invalid
+	_, err = s.GetBackend()
+	assert.EqualError(t, err, "unsupported state backend 'foo', expected one of: workspace, file, vault, s3")
+}
+
+func TestStateIsEmpty(t *testing.T) {
+	var s *State
+	assert.True(t, s.IsEmpty())
+	assert.True(t, (&State{ManagedVariables: ManagedVariables{}}).IsEmpty())
+	assert.False(t, (&State{ManagedVariables: ManagedVariables{VariableKindTerraform: {"foo"}}}).IsEmpty())
+}
+
+func TestComputeVariableFingerprint(t *testing.T) {
+	hash := ComputeVariableFingerprint("key", VariableKindTerraform, "foo", "bar")
+	assert.Equal(t, "874cfbfaec365644b7329c6bb44581f3050dbb71f00627f2934004ac8c13c66f", hash)
+
+	// Hashes are consistent for a given key
+	assert.Equal(t, hash, ComputeVariableFingerprint("key", VariableKindTerraform, "foo", "bar"))
+
+	// Identical values of different variables or keys do not share their hashes
+	assert.NotEqual(t, hash, ComputeVariableFingerprint("other", VariableKindTerraform, "foo", "bar"))
+	assert.NotEqual(t, hash, ComputeVariableFingerprint("key", VariableKindEnvironment, "foo", "bar"))
+	assert.NotEqual(t, hash, ComputeVariableFingerprint("key", VariableKindTerraform, "baz", "bar"))
+}
+
+func TestStateStorageGetFingerprintsKey(t *testing.T) {
+	var s *StateStorage
+	assert.Equal(t, "", s.GetFingerprintsKey())
+
+	key := "foo"
+	assert.Equal(t, "foo", (&StateStorage{FingerprintsKey: &key}).GetFingerprintsKey())
+}
+
+func TestVariableFingerprints(t *testing.T) {
+	f := &VariableFingerprints{}
+	assert.True(t, f.IsEmpty())
+
+	hash := ComputeVariableFingerprint("key", VariableKindTerraform, "foo", "bar")
+	f.Set(VariableKindTerraform, "foo", &VariableFingerprint{Hash: hash, ID: "var-foo"})
+	assert.False(t, f.IsEmpty())
+	assert.Equal(t, "var-foo", f.Get(VariableKindTerraform, "foo").ID)
+	assert.Nil(t, f.Get(VariableKindEnvironment, "foo"))
+
+	s := &State{
+		ManagedVariables:     ManagedVariables{VariableKindTerraform: {"foo"}},
+		VariableFingerprints: f,
+	}
+	s.ForgetVariable(VariableKindTerraform, "foo")
+	assert.True(t, s.IsEmpty())
+
+	var nilFingerprints *VariableFingerprints
+	assert.Nil(t, nilFingerprints.Get(VariableKindTerraform, "foo"))
+	nilFingerprints.Remove(VariableKindTerraform, "foo")
+}
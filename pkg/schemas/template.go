@@ -0,0 +1,11 @@
+package schemas
+
+import (
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Template is a provider type, its content is an HCL template which can reference
+// the values of other variables using tfvar.<name> and envvar.<name>
+type Template struct {
+	Content hcl.Expression `hcl:"content"`
+}
@@ -1,5 +1,7 @@
 package schemas
 
+import "fmt"
+
 // TFC handles Terraform Cloud related configuration
 type TFC struct {
 	Address      *string    `hcl:"address"`
@@ -7,10 +9,23 @@ type TFC struct {
 	Organization *string    `hcl:"organization"`
 	Workspace    *Workspace `hcl:"workspace,block"`
 
-	WorkspaceAutoCreate     *bool `hcl:"workspace-auto-create"`
-	PurgeUnmanagedVariables *bool `hcl:"purge-unmanaged-variables"`
+	WorkspaceAutoCreate             *bool               `hcl:"workspace-auto-create"`
+	PurgeUnmanagedVariables         *bool               `hcl:"purge-unmanaged-variables"`
+	VariablesOwnership              *VariablesOwnership `hcl:"variables-ownership"`
+	SkipUnchangedSensitiveVariables *bool               `hcl:"skip-unchanged-sensitive-variables"`
 }
 
+// VariablesOwnership defines which variables of the workspace TFCW is allowed to remove
+type VariablesOwnership string
+
+const (
+	// VariablesOwnershipAll lets TFCW remove any variable of the workspace
+	VariablesOwnershipAll VariablesOwnership = "all"
+
+	// VariablesOwnershipManaged restricts TFCW to the removal of the variables it has created itself
+	VariablesOwnershipManaged VariablesOwnership = "managed"
+)
+
 // Workspace is used to refer to and configure the workspace
 type Workspace struct {
 	Name             *string `hcl:"name"`
@@ -20,3 +35,17 @@ type Workspace struct {
 	WorkingDirectory *string `hcl:"working-directory"`
 	SSHKey           *string `hcl:"ssh-key"`
 }
+
+// GetVariablesOwnership returns the configured ownership of the variables, defaults to VariablesOwnershipAll
+func (t *TFC) GetVariablesOwnership() (VariablesOwnership, error) {
+	if t == nil || t.VariablesOwnership == nil {
+		return VariablesOwnershipAll, nil
+	}
+
+	switch *t.VariablesOwnership {
+	case VariablesOwnershipAll, VariablesOwnershipManaged:
+		return *t.VariablesOwnership, nil
+	}
+
+	return "", fmt.Errorf("unsupported variables-ownership '%s', expected one of: %s, %s", *t.VariablesOwnership, VariablesOwnershipAll, VariablesOwnershipManaged)
+}
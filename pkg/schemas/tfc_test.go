@@ -0,0 +1,25 @@
+package schemas
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTFCGetVariablesOwnership(t *testing.T) {
+	var tfc *TFC
+	ownership, err := tfc.GetVariablesOwnership()
+	assert.NoError(t, err)
+	assert.Equal(t, VariablesOwnershipAll, ownership)
+
+	managed := VariablesOwnershipManaged
+	tfc = &TFC{VariablesOwnership: &managed}
+	ownership, err = tfc.GetVariablesOwnership()
+	assert.NoError(t, err)
+	assert.Equal(t, VariablesOwnershipManaged, ownership)
+
+	invalid := VariablesOwnership("foo")
+	tfc.VariablesOwnership = &invalid
+	_, err = tfc.GetVariablesOwnership()
+	assert.EqualError(t, err, "unsupported variables-ownership 'foo', expected one of: all, managed")
+}
@@ -0,0 +1,42 @@
+package schemas
+
+// TransformType represents the kind of transformation applied onto the value of a variable
+type TransformType string
+
+const (
+	// TransformTypeBase64Encode base64 encodes the value
+	TransformTypeBase64Encode TransformType = "base64encode"
+
+	// TransformTypeBase64Decode decodes a base64 encoded value
+	TransformTypeBase64Decode TransformType = "base64decode"
+
+	// TransformTypeTrim removes the leading and trailing white spaces of the value
+	TransformTypeTrim TransformType = "trim"
+
+	// TransformTypeJSONEncode JSON encodes the value
+	TransformTypeJSONEncode TransformType = "jsonencode"
+
+	// TransformTypeJSONDecode parses a JSON document and extracts one of its keys
+	TransformTypeJSONDecode TransformType = "jsondecode"
+
+	// TransformTypeLower converts the value to lower case
+	TransformTypeLower TransformType = "lower"
+
+	// TransformTypeUpper converts the value to upper case
+	TransformTypeUpper TransformType = "upper"
+
+	// TransformTypeSHA256 returns the hex encoded SHA256 sum of the value
+	TransformTypeSHA256 TransformType = "sha256"
+
+	// TransformTypeReplace replaces the matches of a regular expression
+	TransformTypeReplace TransformType = "replace"
+)
+
+// Transform is a transformation applied onto the value of a variable once fetched,
+// its type is one of the TransformType values
+type Transform struct {
+	Type        string  `hcl:"type,label"`
+	Key         *string `hcl:"key"`
+	Pattern     *string `hcl:"pattern"`
+	Replacement *string `hcl:"replacement"`
+}
@@ -0,0 +1,12 @@
+package schemas
+
+// Validation contains rules the value(s) of a variable must comply with
+type Validation struct {
+	Regex         *string   `hcl:"regex"`
+	MinLength     *int      `hcl:"min-length"`
+	MaxLength     *int      `hcl:"max-length"`
+	AllowedValues *[]string `hcl:"allowed-values"`
+	JSON          *bool     `hcl:"json"`
+	NotEmpty      *bool     `hcl:"not-empty"`
+	ErrorMessage  *string   `hcl:"error-message"`
+}
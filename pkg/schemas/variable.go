@@ -2,7 +2,15 @@ package schemas
 
 import (
 	"fmt"
+	"sort"
 	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
 // VariableKind represents the kind of variable we want to
@@ -30,17 +38,53 @@ const (
 
 	// VariableProviderVault refers to the 'vault' variable provider
 	VariableProviderVault VariableProvider = "vault"
+
+	// VariableProviderPlugin refers to the 'plugin' variable provider
+	VariableProviderPlugin VariableProvider = "plugin"
+
+	// VariableProviderCommand refers to the 'command' variable provider
+	VariableProviderCommand VariableProvider = "command"
+
+	// VariableProviderFile refers to the 'file' variable provider
+	VariableProviderFile VariableProvider = "file"
+
+	// VariableProviderSOPS refers to the 'sops' variable provider
+	VariableProviderSOPS VariableProvider = "sops"
+
+	// VariableProviderValue refers to the 'value' variable provider
+	VariableProviderValue VariableProvider = "value"
+
+	// VariableProviderTemplate refers to the 'template' variable provider
+	VariableProviderTemplate VariableProvider = "template"
 )
 
 // Variable is a generic handler of variable characteristics
 type Variable struct {
-	Name      string  `hcl:"name,label"`
-	Vault     *Vault  `hcl:"vault,block"`
-	S5        *S5     `hcl:"s5,block"`
-	Env       *Env    `hcl:"env,block"`
-	Sensitive *bool   `hcl:"sensitive"`
-	HCL       *bool   `hcl:"hcl"`
-	TTL       *string `hcl:"ttl"`
+	Name        string    `hcl:"name,label"`
+	Vault       *Vault    `hcl:"vault,block"`
+	S5          *S5       `hcl:"s5,block"`
+	Env         *Env      `hcl:"env,block"`
+	Plugin      *Plugin   `hcl:"plugin,block"`
+	Command     *Command  `hcl:"command,block"`
+	File        *File     `hcl:"file,block"`
+	SOPS        *SOPS     `hcl:"sops,block"`
+	Literal     *Literal  `hcl:"value,block"`
+	Template    *Template `hcl:"template,block"`
+	Sensitive   *bool     `hcl:"sensitive"`
+	HCL         *bool     `hcl:"hcl"`
+	TTL         *string   `hcl:"ttl"`
+	Description *string   `hcl:"description"`
+	Category    *string   `hcl:"category"`
+
+	// Transforms are applied onto the values once fetched, in the order they are defined
+	Transforms []*Transform `hcl:"transform,block"`
+
+	// Validations are checked against the values once transformed, before rendering any of them
+	Validations []*Validation `hcl:"validation,block"`
+
+	// Providers contains the blocks of the providers which are not natively
+	// supported by the schema (registered using tfcw.RegisterProvider)
+	Providers hcl.Body `hcl:",remain"`
 
 	Kind  VariableKind
 	Value string
@@ -50,6 +94,11 @@ type Variable struct {
 type VariableWithValue struct {
 	Variable
 	Value string
+	Lease *VariableLease
+
+	// TypedValue is the value as returned by the providers supporting HCL types, Value being
+	// its serialized form. It is null for plain string values
+	TypedValue cty.Value
 }
 
 // Variables is a slice of *Variable
@@ -58,43 +107,242 @@ type Variables []*Variable
 // VariablesWithValues is a slice of *ComputedVariable
 type VariablesWithValues []*VariableWithValue
 
-// VariableExpirations holds the expiration times of variables, stored within TFC as
-// a jsonmap within an __TFCW_VARIABLES_EXPIRATIONS environment variable (quite hacky..)
+// VariableExpirations holds the expiration times of variables, kept within the State
 type VariableExpirations map[VariableKind]map[string]*VariableExpiration
 
 // VariableExpiration contains the Time To Live (TTL) and when the value of a variable
 // to expire is going to expire
 type VariableExpiration struct {
-	TTL      time.Duration `json:"ttl"`
-	ExpireAt time.Time     `json:"expire_at"`
+	TTL      time.Duration  `json:"ttl"`
+	ExpireAt time.Time      `json:"expire_at"`
+	Lease    *VariableLease `json:"lease,omitempty"`
+}
+
+// ManagedVariables references the variables which have been created by TFCW on a workspace, kept within the State
+type ManagedVariables map[VariableKind][]string
+
+// Add references a variable as managed by TFCW
+func (m ManagedVariables) Add(kind VariableKind, name string) {
+	if m.Has(kind, name) {
+		return
+	}
+	m[kind] = append(m[kind], name)
+	sort.Strings(m[kind])
+}
+
+// Remove dereferences a variable from the ones managed by TFCW
+func (m ManagedVariables) Remove(kind VariableKind, name string) {
+	for i, n := range m[kind] {
+		if n == name {
+			m[kind] = append(m[kind][:i], m[kind][i+1:]...)
+			break
+		}
+	}
+
+	if len(m[kind]) == 0 {
+		delete(m, kind)
+	}
+}
+
+// Has returns whether a variable is managed by TFCW
+func (m ManagedVariables) Has(kind VariableKind, name string) bool {
+	for _, n := range m[kind] {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLeases returns the leases referenced by the expirations
+func (e VariableExpirations) GetLeases() VariableLeases {
+	leases := VariableLeases{}
+	for kind, expirations := range e {
+		for name, expiration := range expirations {
+			if expiration != nil && expiration.Lease != nil {
+				leases.Set(&Variable{Name: name, Kind: kind}, expiration.Lease)
+			}
+		}
+	}
+	return leases
+}
+
+// LeaseTTL can be used as the TTL of a variable in order to derive it from the duration of
+// the lease of its value, optionally multiplied by a factor (eg: lease*0.8)
+const LeaseTTL = "lease"
+
+// VariableLease references the lease of a dynamic secret used as the value of a
+// variable, in order to be able to revoke it once the variable gets rotated
+type VariableLease struct {
+	ID       string           `json:"id"`
+	Duration time.Duration    `json:"duration"`
+	Provider VariableProvider `json:"provider,omitempty"`
+}
+
+// VariableLeases holds the leases obtained whilst fetching the values of the variables
+type VariableLeases map[VariableKind]map[string]*VariableLease
+
+// Set references the lease of a variable
+func (l VariableLeases) Set(v *Variable, lease *VariableLease) {
+	if _, ok := l[v.Kind]; !ok {
+		l[v.Kind] = map[string]*VariableLease{}
+	}
+	l[v.Kind][v.Name] = lease
+}
+
+// Get returns the lease of a variable, nil if it does not have any
+func (l VariableLeases) Get(v *Variable) *VariableLease {
+	if l == nil {
+		return nil
+	}
+	return l[v.Kind][v.Name]
 }
 
-// GetProvider returns the VariableProvider that can be used for processing the variable
-func (v *Variable) GetProvider() (*VariableProvider, error) {
-	configuredProviders := 0
-	var provider *VariableProvider
+// NewVariableWithCtyValue returns a VariableWithValue out of a typed value, see SetTypedValue
+func NewVariableWithCtyValue(v *Variable, value cty.Value) (*VariableWithValue, error) {
+	vv := &VariableWithValue{
+		Variable: *v,
+	}
+
+	if err := vv.SetTypedValue(value); err != nil {
+		return nil, err
+	}
+
+	return vv, nil
+}
+
+// SetTypedValue sets the value of the variable out of a typed value. Strings, numbers and booleans are used
+// as is whilst collections are serialized: as HCL for terraform variables, which then get declared as HCL
+// unless explicitly configured otherwise, and as JSON for environment variables and the terraform ones
+// which are explicitly not HCL
+func (vv *VariableWithValue) SetTypedValue(value cty.Value) error {
+	if value.IsNull() || !value.IsWhollyKnown() {
+		return fmt.Errorf("the value of variable '%s' cannot be null or unknown", vv.Name)
+	}
+
+	if value.Type().IsPrimitiveType() {
+		s, err := convert.Convert(value, cty.String)
+		if err != nil {
+			return fmt.Errorf("unable to convert the value of variable '%s' : %s", vv.Name, err)
+		}
+		vv.Value = s.AsString()
+		vv.TypedValue = value
+		return nil
+	}
+
+	if vv.Kind == VariableKindEnvironment || (vv.HCL != nil && !*vv.HCL) {
+		b, err := ctyjson.Marshal(value, value.Type())
+		if err != nil {
+			return fmt.Errorf("unable to convert the value of variable '%s' : %s", vv.Name, err)
+		}
+		vv.Value = string(b)
+		vv.TypedValue = value
+		return nil
+	}
+
+	vv.Value = string(hclwrite.TokensForValue(value).Bytes())
+	vv.TypedValue = value
+	if vv.HCL == nil {
+		hcl := true
+		vv.HCL = &hcl
+	}
+
+	return nil
+}
+
+// HasStructuredValue returns whether the variable holds a list, map or object value
+func (vv *VariableWithValue) HasStructuredValue() bool {
+	return !vv.TypedValue.IsNull() && !vv.TypedValue.Type().IsPrimitiveType()
+}
+
+// MapValues returns the VariablesWithValues corresponding to either a single 'key' or to
+// a 'keys' mapping of the values returned by a provider, source is used to contextualize errors
+func (v *Variable) MapValues(values map[string]string, key *string, keys *map[string]string, source string) (VariablesWithValues, error) {
+	if key != nil {
+		if value, found := values[*key]; found {
+			return VariablesWithValues{
+				&VariableWithValue{
+					Variable: *v,
+					Value:    value,
+				},
+			}, nil
+		}
+		return nil, fmt.Errorf("key '%s' was not found in %s", *key, source)
+	}
+
+	variablesWithValues := VariablesWithValues{}
+	if keys != nil {
+		for k, variableName := range *keys {
+			if value, found := values[k]; found {
+				vv := &VariableWithValue{
+					Variable: *v,
+					Value:    value,
+				}
+				vv.Name = variableName
+				variablesWithValues = append(variablesWithValues, vv)
+				continue
+			}
+			return nil, fmt.Errorf("key '%s' was not found in %s", k, source)
+		}
+	}
+
+	return variablesWithValues, nil
+}
+
+// HasProviderBlock returns whether a block of the given type is defined amongst
+// the providers which are not natively supported by the schema
+func (v *Variable) HasProviderBlock(blockType string) bool {
+	if v.Providers == nil {
+		return false
+	}
+
+	content, _, diags := v.Providers.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: blockType}},
+	})
+
+	return !diags.HasErrors() && len(content.Blocks) > 0
+}
+
+// DecodeProviderBlock decodes the block of a provider which is not natively
+// supported by the schema into val
+func (v *Variable) DecodeProviderBlock(blockType string, val interface{}) error {
+	if v.Providers == nil {
+		return fmt.Errorf("no '%s' block defined for variable '%s'", blockType, v.Name)
+	}
+
+	content, _, diags := v.Providers.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: blockType}},
+	})
+	if diags.HasErrors() {
+		return diags
+	}
 
-	if v.Env != nil {
-		configuredProviders++
-		p := VariableProviderEnv
-		provider = &p
+	if len(content.Blocks) != 1 {
+		return fmt.Errorf("expected exactly one '%s' block for variable '%s', found %d", blockType, v.Name, len(content.Blocks))
 	}
 
-	if v.S5 != nil {
-		configuredProviders++
-		p := VariableProviderS5
-		provider = &p
+	if diags = gohcl.DecodeBody(content.Blocks[0].Body, nil, val); diags.HasErrors() {
+		return diags
+	}
+
+	return nil
+}
+
+// ValidateProviderBlocks ensures that the providers which are not natively supported
+// by the schema only contain blocks of the given types
+func (v *Variable) ValidateProviderBlocks(blockTypes []string) error {
+	if v.Providers == nil {
+		return nil
 	}
 
-	if v.Vault != nil {
-		configuredProviders++
-		p := VariableProviderVault
-		provider = &p
+	schema := &hcl.BodySchema{}
+	for _, blockType := range blockTypes {
+		schema.Blocks = append(schema.Blocks, hcl.BlockHeaderSchema{Type: blockType})
 	}
 
-	if configuredProviders != 1 {
-		return nil, fmt.Errorf("you can't have more or less than one provider configured per variable. Found %d for '%s'", configuredProviders, v.Name)
+	if _, diags := v.Providers.Content(schema); diags.HasErrors() {
+		return fmt.Errorf("invalid configuration for variable '%s': %s", v.Name, diags.Error())
 	}
 
-	return provider, nil
+	return nil
 }
@@ -1,48 +1,112 @@
 package schemas
 
 import (
-	"fmt"
 	"testing"
 
+	"github.com/hashicorp/hcl/v2/hclsimple"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestVariableGetProviderEnv(t *testing.T) {
-	v := &Variable{
-		Env: &Env{},
-	}
+const testVariableWithProviderBlocks = `
+tfvar "foo" {
+  custom {
+    bar = "baz"
+  }
+}
+`
 
-	p, err := v.GetProvider()
-	assert.Equal(t, nil, err)
-	assert.Equal(t, VariableProviderEnv, *p)
+func decodeTestConfig(t *testing.T, config string) *Config {
+	cfg := &Config{}
+	assert.NoError(t, hclsimple.Decode("tfcw.hcl", []byte(config), nil, cfg))
+	return cfg
 }
 
-func TestVariableGetProviderS5(t *testing.T) {
-	v := &Variable{
-		S5: &S5{},
-	}
+func TestVariableHasProviderBlock(t *testing.T) {
+	v := decodeTestConfig(t, testVariableWithProviderBlocks).GetVariables()[0]
+	assert.True(t, v.HasProviderBlock("custom"))
+	assert.False(t, v.HasProviderBlock("other"))
 
-	p, err := v.GetProvider()
-	assert.Equal(t, nil, err)
-	assert.Equal(t, VariableProviderS5, *p)
+	// Without any remaining block
+	assert.False(t, (&Variable{}).HasProviderBlock("custom"))
 }
 
-func TestVariableGetProviderVault(t *testing.T) {
-	v := &Variable{
-		Vault: &Vault{},
+func TestVariableDecodeProviderBlock(t *testing.T) {
+	v := decodeTestConfig(t, testVariableWithProviderBlocks).GetVariables()[0]
+
+	var custom struct {
+		Bar string `hcl:"bar"`
 	}
+	assert.NoError(t, v.DecodeProviderBlock("custom", &custom))
+	assert.Equal(t, "baz", custom.Bar)
 
-	p, err := v.GetProvider()
-	assert.Equal(t, nil, err)
-	assert.Equal(t, VariableProviderVault, *p)
+	assert.Error(t, v.DecodeProviderBlock("other", &custom))
+	assert.Error(t, (&Variable{Name: "foo"}).DecodeProviderBlock("custom", &custom))
 }
 
-func TestVariableGetProviderInvalid(t *testing.T) {
-	v := &Variable{
-		Name: "foo",
+func TestVariableValidateProviderBlocks(t *testing.T) {
+	v := decodeTestConfig(t, testVariableWithProviderBlocks).GetVariables()[0]
+	assert.NoError(t, v.ValidateProviderBlocks([]string{"custom"}))
+	assert.Error(t, v.ValidateProviderBlocks([]string{"other"}))
+	assert.NoError(t, (&Variable{}).ValidateProviderBlocks([]string{}))
+}
+
+func TestVariableMapValues(t *testing.T) {
+	v := &Variable{Name: "foo"}
+	values := map[string]string{"bar": "baz", "qux": "quux"}
+
+	key := "bar"
+	vv, err := v.MapValues(values, &key, nil, "test")
+	assert.NoError(t, err)
+	assert.Len(t, vv, 1)
+	assert.Equal(t, "foo", vv[0].Name)
+	assert.Equal(t, "baz", vv[0].Value)
+
+	vv, err = v.MapValues(values, nil, &map[string]string{"bar": "BAR", "qux": "QUX"}, "test")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, VariablesWithValues{
+		&VariableWithValue{Variable: Variable{Name: "BAR"}, Value: "baz"},
+		&VariableWithValue{Variable: Variable{Name: "QUX"}, Value: "quux"},
+	}, vv)
+
+	key = "unknown"
+	_, err = v.MapValues(values, &key, nil, "test")
+	assert.EqualError(t, err, "key 'unknown' was not found in test")
+
+	_, err = v.MapValues(values, nil, &map[string]string{"unknown": "BAR"}, "test")
+	assert.EqualError(t, err, "key 'unknown' was not found in test")
+}
+
+func TestVariableExpirationsGetLeases(t *testing.T) {
+	lease := &VariableLease{ID: "foo/bar"}
+	e := VariableExpirations{
+		VariableKindEnvironment: {
+			"foo": &VariableExpiration{Lease: lease},
+			"bar": &VariableExpiration{},
+		},
 	}
-	p, err := v.GetProvider()
-	var emptyProvider *VariableProvider
-	assert.Equal(t, fmt.Errorf("you can't have more or less than one provider configured per variable. Found 0 for 'foo'"), err)
-	assert.Equal(t, emptyProvider, p)
+
+	leases := e.GetLeases()
+	assert.Equal(t, lease, leases.Get(&Variable{Name: "foo", Kind: VariableKindEnvironment}))
+	assert.Nil(t, leases.Get(&Variable{Name: "bar", Kind: VariableKindEnvironment}))
+	assert.Nil(t, leases.Get(&Variable{Name: "foo", Kind: VariableKindTerraform}))
+}
+
+func TestManagedVariables(t *testing.T) {
+	m := ManagedVariables{}
+	m.Add(VariableKindTerraform, "foo")
+	m.Add(VariableKindTerraform, "bar")
+	m.Add(VariableKindTerraform, "foo")
+	m.Add(VariableKindEnvironment, "foo")
+	assert.Equal(t, ManagedVariables{
+		VariableKindTerraform:   {"bar", "foo"},
+		VariableKindEnvironment: {"foo"},
+	}, m)
+
+	assert.True(t, m.Has(VariableKindTerraform, "bar"))
+	assert.False(t, m.Has(VariableKindEnvironment, "bar"))
+
+	m.Remove(VariableKindTerraform, "bar")
+	m.Remove(VariableKindEnvironment, "foo")
+	m.Remove(VariableKindEnvironment, "baz")
+	assert.Equal(t, ManagedVariables{VariableKindTerraform: {"foo"}}, m)
 }
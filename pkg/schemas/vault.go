@@ -2,12 +2,51 @@ package schemas
 
 // Vault is a provider type
 type Vault struct {
-	Address *string            `hcl:"address"`
-	Token   *string            `hcl:"token"`
-	Method  *string            `hcl:"method"`
-	Params  *map[string]string `hcl:"params"`
-	Path    *string            `hcl:"path"`
-	Key     *string            `hcl:"key"`
-	Keys    *map[string]string `hcl:"keys"`
-	Values  map[string]string
+	Address   *string            `hcl:"address"`
+	Token     *string            `hcl:"token"`
+	Namespace *string            `hcl:"namespace"`
+	Auth      *VaultAuth         `hcl:"auth,block"`
+	Method    *string            `hcl:"method"`
+	Params    *map[string]string `hcl:"params"`
+	Path      *string            `hcl:"path"`
+	Mount     *string            `hcl:"mount"`
+	Version   *int               `hcl:"version"`
+	Key       *string            `hcl:"key"`
+	Keys      *map[string]string `hcl:"keys"`
+	Values    map[string]string
 }
+
+// VaultAuth handles the configuration of the method used to login onto Vault
+type VaultAuth struct {
+	Method     VaultAuthMethod `hcl:"method"`
+	Mount      *string         `hcl:"mount"`
+	Role       *string         `hcl:"role"`
+	RoleID     *string         `hcl:"role-id"`
+	SecretID   *string         `hcl:"secret-id"`
+	JWT        *string         `hcl:"jwt"`
+	JWTPath    *string         `hcl:"jwt-path"`
+	Username   *string         `hcl:"username"`
+	Password   *string         `hcl:"password"`
+	ClientCert *string         `hcl:"client-cert"`
+	ClientKey  *string         `hcl:"client-key"`
+}
+
+// VaultAuthMethod represents a Vault auth method
+type VaultAuthMethod string
+
+const (
+	// VaultAuthMethodAppRole refers to the 'approle' Vault auth method
+	VaultAuthMethodAppRole VaultAuthMethod = "approle"
+
+	// VaultAuthMethodKubernetes refers to the 'kubernetes' Vault auth method
+	VaultAuthMethodKubernetes VaultAuthMethod = "kubernetes"
+
+	// VaultAuthMethodJWT refers to the 'jwt' Vault auth method
+	VaultAuthMethodJWT VaultAuthMethod = "jwt"
+
+	// VaultAuthMethodUserpass refers to the 'userpass' Vault auth method
+	VaultAuthMethodUserpass VaultAuthMethod = "userpass"
+
+	// VaultAuthMethodCert refers to the 'cert' Vault auth method
+	VaultAuthMethodCert VaultAuthMethod = "cert"
+)
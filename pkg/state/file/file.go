@@ -0,0 +1,61 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+// Store keeps the state of TFCW within a local JSON file
+type Store struct {
+	Path string
+}
+
+// Load returns the state stored within the file, an empty one if the file does not exist
+func (s *Store) Load() (*schemas.State, error) {
+	state := &schemas.State{}
+	content, err := ioutil.ReadFile(filepath.Clean(s.Path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+
+	if err = json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("unable to parse the state stored in '%s' : %s", s.Path, err)
+	}
+
+	return state, nil
+}
+
+// Save writes the state into the file
+func (s *Store) Save(state *schemas.State) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.Path, content, 0o600)
+}
+
+// Delete removes the file
+func (s *Store) Delete() error {
+	if err := os.Remove(s.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// String returns a description of the store
+func (s *Store) String() string {
+	return fmt.Sprintf("file '%s'", s.Path)
+}
@@ -0,0 +1,39 @@
+package file
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	s := &Store{Path: filepath.Join(t.TempDir(), "foo", "state.json")}
+
+	// Unexistent file
+	state, err := s.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, &schemas.State{}, state)
+
+	state.ManagedVariables = schemas.ManagedVariables{schemas.VariableKindTerraform: {"foo"}}
+	state.VariableExpirations = schemas.VariableExpirations{
+		schemas.VariableKindTerraform: {
+			"foo": &schemas.VariableExpiration{TTL: time.Minute, ExpireAt: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+	assert.NoError(t, s.Save(state))
+
+	loadedState, err := s.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, state, loadedState)
+
+	assert.NoError(t, s.Delete())
+	assert.NoError(t, s.Delete())
+
+	assert.NoError(t, ioutil.WriteFile(s.Path, []byte("foo"), 0o600))
+	_, err = s.Load()
+	assert.EqualError(t, err, "unable to parse the state stored in '"+s.Path+"' : invalid character 'o' in literal false (expecting 'a')")
+}
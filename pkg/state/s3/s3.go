@@ -0,0 +1,111 @@
+package s3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awsS3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+// Store keeps the state of TFCW within an object of an S3 (compatible) bucket
+type Store struct {
+	Client s3iface.S3API
+	Bucket string
+	Key    string
+}
+
+// NewStore returns a Store using the AWS credentials available in the environment,
+// a custom endpoint can be configured in order to use S3 compatible services
+func NewStore(cfg *schemas.StateS3) (*Store, error) {
+	awsCfg := aws.NewConfig()
+	if cfg.Region != nil {
+		awsCfg = awsCfg.WithRegion(*cfg.Region)
+	}
+
+	if cfg.Endpoint != nil {
+		awsCfg = awsCfg.WithEndpoint(*cfg.Endpoint)
+	}
+
+	if cfg.ForcePathStyle != nil {
+		awsCfg = awsCfg.WithS3ForcePathStyle(*cfg.ForcePathStyle)
+	}
+
+	sess, err := session.NewSession(awsCfg)
+	if err != nil {
+		return nil, fmt.Errorf("error creating AWS session: %s", err)
+	}
+
+	return &Store{
+		Client: awsS3.New(sess),
+		Bucket: cfg.Bucket,
+		Key:    cfg.Key,
+	}, nil
+}
+
+// Load returns the state stored within the object, an empty one if the object does not exist
+func (s *Store) Load() (*schemas.State, error) {
+	state := &schemas.State{}
+	output, err := s.Client.GetObject(&awsS3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == awsS3.ErrCodeNoSuchKey {
+			return state, nil
+		}
+		return nil, fmt.Errorf("s3 error : %s", err)
+	}
+	defer output.Body.Close()
+
+	content, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(content, state); err != nil {
+		return nil, fmt.Errorf("unable to parse the state stored in %s : %s", s, err)
+	}
+
+	return state, nil
+}
+
+// Save writes the state into the object
+func (s *Store) Save(state *schemas.State) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.Client.PutObject(&awsS3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(s.Key),
+		Body:        bytes.NewReader(content),
+		ContentType: aws.String("application/json"),
+	}); err != nil {
+		return fmt.Errorf("s3 error : %s", err)
+	}
+	return nil
+}
+
+// Delete removes the object
+func (s *Store) Delete() error {
+	if _, err := s.Client.DeleteObject(&awsS3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Key),
+	}); err != nil {
+		return fmt.Errorf("s3 error : %s", err)
+	}
+	return nil
+}
+
+// String returns a description of the store
+func (s *Store) String() string {
+	return fmt.Sprintf("s3 object 's3://%s/%s'", s.Bucket, s.Key)
+}
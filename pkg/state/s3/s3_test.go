@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestS3Server returns a minimal S3 compatible server storing objects in memory
+func createTestS3Server(t *testing.T) *httptest.Server {
+	mutex := sync.Mutex{}
+	objects := map[string][]byte{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			object, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+				return
+			}
+			_, _ = w.Write(object)
+		case http.MethodPut:
+			objects[r.URL.Path], _ = ioutil.ReadAll(r.Body)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStore(t *testing.T) {
+	server := createTestS3Server(t)
+	os.Setenv("AWS_ACCESS_KEY_ID", "foo")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "bar")
+
+	s, err := NewStore(&schemas.StateS3{
+		Bucket:         "foo",
+		Key:            "bar/state.json",
+		Region:         pointy.String("us-east-1"),
+		Endpoint:       pointy.String(server.URL),
+		ForcePathStyle: pointy.Bool(true),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "s3 object 's3://foo/bar/state.json'", s.String())
+
+	// Unexistent object
+	state, err := s.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, &schemas.State{}, state)
+
+	state.ManagedVariables = schemas.ManagedVariables{schemas.VariableKindTerraform: {"foo"}}
+	assert.NoError(t, s.Save(state))
+
+	loadedState, err := s.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, state, loadedState)
+
+	assert.NoError(t, s.Delete())
+	state, err = s.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, &schemas.State{}, state)
+}
@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	providerVault "github.com/mvisonneau/tfcw/pkg/providers/vault"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+// stateKey is the key of the secret holding the JSON encoded state
+const stateKey = "state"
+
+// Store keeps the state of TFCW within a Vault KV secret, the secret is read from
+// and written onto a kv-v2 secret engine when a mount is defined
+type Store struct {
+	Client *providerVault.Client
+	Mount  *string
+	Path   string
+}
+
+// Load returns the state stored within the secret, an empty one if the secret does not exist
+func (s *Store) Load() (*schemas.State, error) {
+	state := &schemas.State{}
+	secret, err := s.Client.Logical().Read(s.getDataPath())
+	if err != nil {
+		return nil, fmt.Errorf("vault error : %s", err)
+	}
+
+	if secret == nil || secret.Data == nil {
+		return state, nil
+	}
+
+	data := secret.Data
+	if s.Mount != nil {
+		var ok bool
+		if data, ok = secret.Data["data"].(map[string]interface{}); !ok {
+			// Deleted versions of kv-v2 secrets do not hold any data
+			return state, nil
+		}
+	}
+
+	value, ok := data[stateKey].(string)
+	if !ok {
+		return nil, fmt.Errorf("no '%s' key found in secret : %s", stateKey, s.getDataPath())
+	}
+
+	if err = json.Unmarshal([]byte(value), state); err != nil {
+		return nil, fmt.Errorf("unable to parse the state stored in secret %s : %s", s.getDataPath(), err)
+	}
+
+	return state, nil
+}
+
+// Save writes the state into the secret
+func (s *Store) Save(state *schemas.State) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{stateKey: string(value)}
+	if s.Mount != nil {
+		data = map[string]interface{}{"data": data}
+	}
+
+	if _, err = s.Client.Logical().Write(s.getDataPath(), data); err != nil {
+		return fmt.Errorf("vault error : %s", err)
+	}
+	return nil
+}
+
+// Delete removes the secret, including all its versions on kv-v2 secret engines
+func (s *Store) Delete() error {
+	path := s.getDataPath()
+	if s.Mount != nil {
+		path = fmt.Sprintf("%s/metadata/%s", strings.Trim(*s.Mount, "/"), strings.TrimLeft(s.Path, "/"))
+	}
+
+	if _, err := s.Client.Logical().Delete(path); err != nil {
+		return fmt.Errorf("vault error : %s", err)
+	}
+	return nil
+}
+
+// String returns a description of the store
+func (s *Store) String() string {
+	return fmt.Sprintf("vault secret '%s'", s.getDataPath())
+}
+
+func (s *Store) getDataPath() string {
+	if s.Mount != nil {
+		return fmt.Sprintf("%s/data/%s", strings.Trim(*s.Mount, "/"), strings.TrimLeft(s.Path, "/"))
+	}
+	return s.Path
+}
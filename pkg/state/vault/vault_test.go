@@ -0,0 +1,88 @@
+// There seems to be a bug in a lib importer by hashicorp/vault/api that prevents the test from running
+// correctly on darwin..
+//
+//go:build !darwin
+// +build !darwin
+
+package vault
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	providerVault "github.com/mvisonneau/tfcw/pkg/providers/vault"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestVaultServer returns a server storing secrets in memory, kv-v2
+// secrets are stored as is, alongside their "data" envelope
+func createTestVaultServer(t *testing.T) *httptest.Server {
+	mutex := sync.Mutex{}
+	secrets := map[string]map[string]interface{}{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		path := r.URL.Path
+		switch r.Method {
+		case http.MethodGet:
+			secret, ok := secrets[path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": secret})
+		case http.MethodPut, http.MethodPost:
+			body, _ := ioutil.ReadAll(r.Body)
+			data := map[string]interface{}{}
+			assert.NoError(t, json.Unmarshal(body, &data))
+			secrets[path] = data
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			// Deleting the metadata of kv-v2 secrets removes all their versions
+			delete(secrets, strings.Replace(path, "/metadata/", "/data/", 1))
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStore(t *testing.T) {
+	server := createTestVaultServer(t)
+	c, err := providerVault.GetClient(server.URL, "_", "", nil)
+	assert.NoError(t, err)
+
+	for _, s := range []*Store{
+		{Client: c, Path: "secret/foo/state"},
+		{Client: c, Mount: pointy.String("/kv/"), Path: "foo/state"},
+	} {
+		// Unexistent secret
+		state, err := s.Load()
+		assert.NoError(t, err)
+		assert.Equal(t, &schemas.State{}, state)
+
+		state.ManagedVariables = schemas.ManagedVariables{schemas.VariableKindTerraform: {"foo"}}
+		assert.NoError(t, s.Save(state))
+
+		loadedState, err := s.Load()
+		assert.NoError(t, err)
+		assert.Equal(t, state, loadedState)
+
+		assert.NoError(t, s.Delete())
+		state, err = s.Load()
+		assert.NoError(t, err)
+		assert.Equal(t, &schemas.State{}, state)
+	}
+
+	assert.Equal(t, "vault secret 'kv/data/foo/state'", (&Store{Mount: pointy.String("kv"), Path: "foo/state"}).String())
+}
@@ -8,29 +8,33 @@ import (
 
 	tfc "github.com/hashicorp/go-tfe"
 	"github.com/jpillora/backoff"
-	providerEnv "github.com/mvisonneau/tfcw/pkg/providers/env"
 	providerS5 "github.com/mvisonneau/tfcw/pkg/providers/s5"
+	providerTemplate "github.com/mvisonneau/tfcw/pkg/providers/template"
 	providerVault "github.com/mvisonneau/tfcw/pkg/providers/vault"
 	"github.com/mvisonneau/tfcw/pkg/schemas"
 )
 
 // Client aggregates provider clients
 type Client struct {
-	Vault                   *providerVault.Client
-	S5                      *providerS5.Client
-	Env                     *providerEnv.Client
+	Providers               map[schemas.VariableProvider]Provider
 	TFC                     *tfc.Client
 	Context                 context.Context
 	ProcessedVariablesMutex sync.Mutex
 	ProcessedVariables      map[string]schemas.VariableKind
 	Backoff                 *backoff.Backoff
+
+	fetchesMutex sync.Mutex
+	fetches      map[*schemas.Variable]*variableValuesFetch
+
+	// fingerprintsMutex protects the fingerprints whilst the variables are being rendered concurrently
+	fingerprintsMutex sync.Mutex
 }
 
 // NewClient instantiate a Client from a provider Config
 func NewClient(cfg *schemas.Config) (c *Client, err error) {
-	vaultClient, err := getVaultClient(cfg)
+	providers, err := getProviders(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("error getting vault client: %s", err)
+		return nil, err
 	}
 
 	tfcClient, err := getTFCClient(cfg)
@@ -39,9 +43,7 @@ func NewClient(cfg *schemas.Config) (c *Client, err error) {
 	}
 
 	c = &Client{
-		Vault:              vaultClient,
-		S5:                 getS5Client(cfg),
-		Env:                &providerEnv.Client{},
+		Providers:          providers,
 		TFC:                tfcClient,
 		Context:            context.Background(),
 		ProcessedVariables: map[string]schemas.VariableKind{},
@@ -53,32 +55,25 @@ func NewClient(cfg *schemas.Config) (c *Client, err error) {
 		},
 	}
 
+	// Templates get the values of the variables they reference through the client
+	if p, ok := providers[schemas.VariableProviderTemplate].(*providerTemplate.Client); ok {
+		p.Resolve = c.getVariableValueResolver(cfg)
+	}
+
 	return
 }
 
-func getVaultClient(cfg *schemas.Config) (c *providerVault.Client, err error) {
-	if isVaultClientRequired(cfg) {
-		// Initializing Vault client with default values
-		var vaultAddress, vaultToken string
-		if cfg.Defaults != nil {
-			if cfg.Defaults.Vault != nil {
-				if cfg.Defaults.Vault.Address != nil {
-					vaultAddress = *cfg.Defaults.Vault.Address
-				}
-
-				if cfg.Defaults.Vault.Token != nil {
-					vaultToken = *cfg.Defaults.Vault.Token
-				}
-			}
-		}
-
-		c, err = providerVault.GetClient(vaultAddress, vaultToken)
+func getVaultClient(cfg *schemas.Config) (*providerVault.Pool, error) {
+	// Clients are initialized lazily, using the default values unless overridden by the variables
+	if cfg.Defaults != nil {
+		return providerVault.NewPool(cfg.Defaults.Vault), nil
 	}
-	return
+	return providerVault.NewPool(nil), nil
 }
 
-func getS5Client(cfg *schemas.Config) (c *providerS5.Client) {
-	c = &providerS5.Client{}
+// GetS5Client returns a s5 client using the cipher engines configured in the defaults
+func GetS5Client(cfg *schemas.Config) (c *providerS5.Client) {
+	c = &providerS5.Client{WorkingDir: cfg.Runtime.WorkingDir}
 	if cfg.Defaults != nil && cfg.Defaults.S5 != nil {
 		if cfg.Defaults.S5.CipherEngineType != nil {
 			c.CipherEngineType = cfg.Defaults.S5.CipherEngineType
@@ -86,6 +81,9 @@ func getS5Client(cfg *schemas.Config) (c *providerS5.Client) {
 		if cfg.Defaults.S5.CipherEngineAES != nil {
 			c.CipherEngineAES = cfg.Defaults.S5.CipherEngineAES
 		}
+		if cfg.Defaults.S5.CipherEngineAge != nil {
+			c.CipherEngineAge = cfg.Defaults.S5.CipherEngineAge
+		}
 		if cfg.Defaults.S5.CipherEngineAWS != nil {
 			c.CipherEngineAWS = cfg.Defaults.S5.CipherEngineAWS
 		}
@@ -109,12 +107,3 @@ func getTFCClient(cfg *schemas.Config) (c *tfc.Client, err error) {
 	})
 	return
 }
-
-func isVaultClientRequired(cfg *schemas.Config) bool {
-	for _, v := range append(cfg.TerraformVariables, cfg.EnvironmentVariables...) {
-		if v.Vault != nil {
-			return true
-		}
-	}
-	return false
-}
@@ -3,9 +3,6 @@ package tfcw
 import (
 	"testing"
 
-	providerEnv "github.com/mvisonneau/tfcw/pkg/providers/env"
-	providerS5 "github.com/mvisonneau/tfcw/pkg/providers/s5"
-	providerVault "github.com/mvisonneau/tfcw/pkg/providers/vault"
 	"github.com/mvisonneau/tfcw/pkg/schemas"
 	"github.com/stretchr/testify/assert"
 )
@@ -24,38 +21,7 @@ func getTestConfig() (cfg *schemas.Config) {
 func TestNewClient(t *testing.T) {
 	c, err := NewClient(getTestConfig())
 	assert.Equal(t, nil, err)
-	assert.IsType(t, &providerVault.Client{}, c.Vault)
-	assert.IsType(t, &providerS5.Client{}, c.S5)
-	assert.IsType(t, &providerEnv.Client{}, c.Env)
-}
-
-func TestIsVaultClientRequired(t *testing.T) {
-	// Validate Vault client is not required if config is empty
-	cfg := &schemas.Config{}
-
-	assert.Equal(t, false, isVaultClientRequired(cfg))
-
-	// Validate Vault client is not required if config contains other variables with
-	// different providers is empty
-	s5CipherEngineType := schemas.S5CipherEngineTypeAES
-	cfg.EnvironmentVariables = schemas.Variables{
-		&schemas.Variable{
-			S5: &schemas.S5{
-				CipherEngineType: &s5CipherEngineType,
-			},
-		},
-	}
-	assert.Equal(t, false, isVaultClientRequired(cfg))
-
-	path := "foo"
-	cfg.EnvironmentVariables = schemas.Variables{
-		&schemas.Variable{
-			Vault: &schemas.Vault{
-				Path: &path,
-			},
-		},
-	}
-	assert.Equal(t, true, isVaultClientRequired(cfg))
+	assert.Len(t, c.Providers, 0)
 }
 
 func TestGetVaultClient(t *testing.T) {
@@ -76,7 +42,10 @@ func TestGetVaultClient(t *testing.T) {
 		},
 	}
 
-	c, err := getVaultClient(cfg)
+	p, err := getVaultClient(cfg)
+	assert.Equal(t, nil, err)
+
+	c, err := p.GetClient(cfg.EnvironmentVariables[0].Vault)
 	assert.Equal(t, nil, err)
 	assert.Equal(t, fooString, c.Address())
 	assert.Equal(t, fooString, c.Token())
@@ -85,6 +54,7 @@ func TestGetVaultClient(t *testing.T) {
 func TestGetS5Client(t *testing.T) {
 	cipherEngineType := schemas.S5CipherEngineTypeAES
 	cipherEngineAES := schemas.S5CipherEngineAES{}
+	cipherEngineAge := schemas.S5CipherEngineAge{}
 	cipherEngineAWS := schemas.S5CipherEngineAWS{}
 	cipherEngineGCP := schemas.S5CipherEngineGCP{}
 	cipherEnginePGP := schemas.S5CipherEnginePGP{}
@@ -95,6 +65,7 @@ func TestGetS5Client(t *testing.T) {
 			S5: &schemas.S5{
 				CipherEngineType:  &cipherEngineType,
 				CipherEngineAES:   &cipherEngineAES,
+				CipherEngineAge:   &cipherEngineAge,
 				CipherEngineAWS:   &cipherEngineAWS,
 				CipherEngineGCP:   &cipherEngineGCP,
 				CipherEnginePGP:   &cipherEnginePGP,
@@ -103,9 +74,10 @@ func TestGetS5Client(t *testing.T) {
 		},
 	}
 
-	c := getS5Client(cfg)
+	c := GetS5Client(cfg)
 	assert.Equal(t, cipherEngineType, *c.CipherEngineType)
 	assert.Equal(t, cipherEngineAES, *c.CipherEngineAES)
+	assert.Equal(t, cipherEngineAge, *c.CipherEngineAge)
 	assert.Equal(t, cipherEngineAWS, *c.CipherEngineAWS)
 	assert.Equal(t, cipherEngineGCP, *c.CipherEngineGCP)
 	assert.Equal(t, cipherEnginePGP, *c.CipherEnginePGP)
@@ -0,0 +1,261 @@
+package tfcw
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tfc "github.com/hashicorp/go-tfe"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+// VariableChangeAction represents what TFCW would do onto a variable of the workspace
+type VariableChangeAction string
+
+const (
+	// VariableChangeActionCreate refers to a variable which is going to be created
+	VariableChangeActionCreate VariableChangeAction = "create"
+
+	// VariableChangeActionUpdate refers to a variable which is going to be updated
+	VariableChangeActionUpdate VariableChangeAction = "update"
+
+	// VariableChangeActionDelete refers to a variable which is going to be purged
+	VariableChangeActionDelete VariableChangeAction = "delete"
+
+	// VariableChangeActionSkip refers to a variable which is going to be left untouched
+	VariableChangeActionSkip VariableChangeAction = "skip"
+)
+
+// VariableChange describes what TFCW would do onto a variable of the workspace, values are always masked
+type VariableChange struct {
+	Action  VariableChangeAction `json:"action"`
+	Kind    schemas.VariableKind `json:"kind"`
+	Name    string               `json:"name"`
+	Value   string               `json:"value,omitempty"`
+	Reasons []string             `json:"reasons,omitempty"`
+}
+
+// VariablesDiff holds the changes TFCW would apply onto the variables of the workspace
+type VariablesDiff []*VariableChange
+
+// DiffVariables compares the variables defined in the config with the ones currently set on the workspace and
+// returns the changes a rendering would apply. The values of the variables to update have to be fetched, the
+// leases obtained whilst doing so are revoked straight away
+func (c *Client) DiffVariables(cfg *schemas.Config, w *tfc.Workspace, forceUpdate bool) (diff VariablesDiff, err error) {
+	if err = c.ValidateVariables(cfg); err != nil {
+		return
+	}
+
+	ownership, err := cfg.TFC.GetVariablesOwnership()
+	if err != nil {
+		return
+	}
+
+	if _, err = cfg.GetSkipUnchangedSensitiveVariables(); err != nil {
+		return
+	}
+
+	_, state, err := c.loadState(cfg, w)
+	if err != nil {
+		return
+	}
+
+	existingVariables, _, err := c.listVariables(w)
+	if err != nil {
+		return nil, fmt.Errorf("terraform cloud: %s", err)
+	}
+
+	return c.diffVariables(cfg, existingVariables, state, ownership, forceUpdate)
+}
+
+func (c *Client) diffVariables(cfg *schemas.Config, existingVariables TFCVariables, state *schemas.State, ownership schemas.VariablesOwnership, forceUpdate bool) (diff VariablesDiff, err error) {
+	variablesToUpdate := cfg.GetVariables()
+	if !forceUpdate {
+		if variablesToUpdate, err = cfg.GetVariablesToUpdate(state.VariableExpirations); err != nil {
+			return
+		}
+
+		if variablesToUpdate, err = c.withReferencedVariables(cfg, variablesToUpdate); err != nil {
+			return
+		}
+	}
+
+	variablesWithValues, leases, err := c.fetchAndValidateVariablesWithValues(cfg, variablesToUpdate)
+	c.revokeUnusedLeases(variablesToUpdate, leases)
+	if err != nil {
+		return
+	}
+
+	drifts := map[schemas.VariableKind]map[string]string{}
+	for _, drift := range c.getVariablesDrift(cfg, cfg.GetVariables(), existingVariables, state.VariableFingerprints) {
+		if _, ok := drifts[drift.Kind]; !ok {
+			drifts[drift.Kind] = map[string]string{}
+		}
+		drifts[drift.Kind][drift.Name] = drift.Reason
+	}
+
+	// Variables which are still valid are not going to be fetched nor rendered
+	for _, v := range getVariablesNotToUpdate(cfg.GetVariables(), variablesToUpdate) {
+		for _, variableName := range c.getRenderedVariableNames(v) {
+			reasons := []string{}
+			if expiration := getVariableExpiration(state.VariableExpirations, v, variableName); expiration != nil {
+				reasons = append(reasons, fmt.Sprintf("ttl valid until %s", expiration.ExpireAt.Format(time.RFC3339)))
+			}
+
+			if reason, ok := drifts[v.Kind][variableName]; ok {
+				reasons = append(reasons, reason)
+			}
+
+			diff = append(diff, &VariableChange{
+				Action:  VariableChangeActionSkip,
+				Kind:    v.Kind,
+				Name:    variableName,
+				Reasons: reasons,
+			})
+		}
+	}
+
+	for _, v := range variablesWithValues {
+		setVariableDefaults(cfg, v)
+		change := &VariableChange{
+			Kind:  v.Kind,
+			Name:  v.Name,
+			Value: secureSensitiveString(v.Value),
+		}
+
+		existingVariable := existingVariables[getCategoryType(v.Kind)][v.Name]
+		if existingVariable == nil {
+			change.Action = VariableChangeActionCreate
+			diff = append(diff, change)
+			continue
+		}
+
+		var hash string
+		if hash, err = getFingerprintHash(cfg, v); err != nil {
+			return
+		}
+
+		change.Reasons = getVariableChanges(v, existingVariable, state.VariableFingerprints.Get(v.Kind, v.Name), hash)
+		switch {
+		case len(change.Reasons) > 0:
+			change.Action = VariableChangeActionUpdate
+		case forceUpdate:
+			change.Action = VariableChangeActionUpdate
+			change.Reasons = []string{"forced"}
+		default:
+			change.Action = VariableChangeActionSkip
+			change.Reasons = []string{"unchanged"}
+			change.Value = ""
+		}
+
+		if reason, ok := drifts[v.Kind][v.Name]; ok {
+			change.Reasons = append(change.Reasons, reason)
+		}
+
+		diff = append(diff, change)
+	}
+
+	if cfg.TFC.PurgeUnmanagedVariables != nil && *cfg.TFC.PurgeUnmanagedVariables {
+		for _, v := range c.getVariablesToPurge(cfg.GetVariables(), existingVariables, state, ownership) {
+			diff = append(diff, &VariableChange{
+				Action: VariableChangeActionDelete,
+				Kind:   getVariableKind(v.Category),
+				Name:   v.Key,
+			})
+		}
+	}
+
+	sort.SliceStable(diff, func(i, j int) bool {
+		if diff[i].Kind != diff[j].Kind {
+			return diff[i].Kind < diff[j].Kind
+		}
+		return diff[i].Name < diff[j].Name
+	})
+
+	return
+}
+
+// getRenderedVariableNames returns the names of the variables a configured variable gets rendered as on TFC:
+// the ones mapped through 'keys' for the providers supporting it, its own name otherwise
+func (c *Client) getRenderedVariableNames(v *schemas.Variable) []string {
+	if names := c.getVariableNames(v); len(names) > 1 {
+		return names[1:]
+	}
+	return []string{v.Name}
+}
+
+// getVariableExpiration returns the expiration of a rendered variable, the expirations of the variables
+// mapped through 'keys' being tracked under the name of the configured variable
+func getVariableExpiration(expirations schemas.VariableExpirations, v *schemas.Variable, variableName string) *schemas.VariableExpiration {
+	if expiration, ok := expirations[v.Kind][variableName]; ok {
+		return expiration
+	}
+	return expirations[v.Kind][v.Name]
+}
+
+// getVariablesNotToUpdate returns the variables which are not part of the ones to update
+func getVariablesNotToUpdate(vars, variablesToUpdate schemas.Variables) (variables schemas.Variables) {
+	for _, v := range vars {
+		toUpdate := false
+		for _, u := range variablesToUpdate {
+			if u == v {
+				toUpdate = true
+				break
+			}
+		}
+
+		if !toUpdate {
+			variables = append(variables, v)
+		}
+	}
+	return
+}
+
+// Count returns the number of changes for each action
+func (d VariablesDiff) Count() map[VariableChangeAction]int {
+	count := map[VariableChangeAction]int{
+		VariableChangeActionCreate: 0,
+		VariableChangeActionUpdate: 0,
+		VariableChangeActionDelete: 0,
+		VariableChangeActionSkip:   0,
+	}
+
+	for _, change := range d {
+		count[change.Action]++
+	}
+	return count
+}
+
+// String returns a human readable representation of the diff, similar to a plan
+func (d VariablesDiff) String() string {
+	symbols := map[VariableChangeAction]string{
+		VariableChangeActionCreate: "+",
+		VariableChangeActionUpdate: "~",
+		VariableChangeActionDelete: "-",
+		VariableChangeActionSkip:   "=",
+	}
+
+	var sb strings.Builder
+	for _, change := range d {
+		sb.WriteString(fmt.Sprintf("  %s %s (%s)", symbols[change.Action], change.Name, change.Kind))
+		if len(change.Value) > 0 {
+			sb.WriteString(fmt.Sprintf(" : %s", change.Value))
+		}
+
+		if len(change.Reasons) > 0 {
+			sb.WriteString(fmt.Sprintf(" [%s]", strings.Join(change.Reasons, ", ")))
+		}
+		sb.WriteString("\n")
+	}
+
+	count := d.Count()
+	sb.WriteString(fmt.Sprintf("\n%d to create, %d to update, %d to delete, %d to skip\n",
+		count[VariableChangeActionCreate],
+		count[VariableChangeActionUpdate],
+		count[VariableChangeActionDelete],
+		count[VariableChangeActionSkip],
+	))
+
+	return sb.String()
+}
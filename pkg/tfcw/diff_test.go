@@ -0,0 +1,172 @@
+package tfcw
+
+import (
+	"testing"
+	"time"
+
+	tfc "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	providerValue "github.com/mvisonneau/tfcw/pkg/providers/value"
+	providerVault "github.com/mvisonneau/tfcw/pkg/providers/vault"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffVariables(t *testing.T) {
+	cfg := &schemas.Config{}
+	assert.NoError(t, hclsimple.Decode("tfcw.hcl", []byte(`
+tfc {
+  purge-unmanaged-variables = true
+}
+
+tfvar "new" {
+  value {
+    value = "foo"
+  }
+}
+
+tfvar "unchanged" {
+  sensitive = false
+  value {
+    value = "foo"
+  }
+}
+
+tfvar "updated" {
+  value {
+    value = "foobar"
+  }
+}
+
+envvar "TTL" {
+  ttl = "1h"
+  value {
+    value = "foo"
+  }
+}
+`), nil, cfg))
+
+	state := &schemas.State{
+		VariableExpirations: schemas.VariableExpirations{
+			schemas.VariableKindEnvironment: {
+				"TTL": &schemas.VariableExpiration{TTL: time.Hour, ExpireAt: time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+		ManagedVariables:     schemas.ManagedVariables{},
+		VariableFingerprints: &schemas.VariableFingerprints{},
+	}
+
+	hash := schemas.ComputeVariableFingerprint("", schemas.VariableKindTerraform, "updated", "bar")
+	state.VariableFingerprints.Set(schemas.VariableKindTerraform, "updated", &schemas.VariableFingerprint{Hash: hash, ID: "var-updated"})
+
+	e := TFCVariables{
+		tfc.CategoryTerraform: {
+			"unchanged": &tfc.Variable{ID: "var-unchanged", Key: "unchanged", Value: "foo", Category: tfc.CategoryTerraform},
+			"updated":   &tfc.Variable{ID: "var-updated", Key: "updated", Sensitive: true, Category: tfc.CategoryTerraform},
+			"unmanaged": &tfc.Variable{ID: "var-unmanaged", Key: "unmanaged", Value: "foo", Category: tfc.CategoryTerraform},
+		},
+		tfc.CategoryEnv: {
+			"TTL": &tfc.Variable{ID: "var-ttl", Key: "TTL", Value: "foo", Category: tfc.CategoryEnv},
+		},
+	}
+
+	c := &Client{
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderValue: &providerValue.Client{},
+		},
+		ProcessedVariables: map[string]schemas.VariableKind{},
+	}
+
+	diff, err := c.diffVariables(cfg, e, state, schemas.VariablesOwnershipAll, false)
+	assert.NoError(t, err)
+	assert.Equal(t, VariablesDiff{
+		{Action: VariableChangeActionSkip, Kind: schemas.VariableKindEnvironment, Name: "TTL", Reasons: []string{"ttl valid until 2100-01-01T00:00:00Z"}},
+		{Action: VariableChangeActionCreate, Kind: schemas.VariableKindTerraform, Name: "new", Value: "**********"},
+		{Action: VariableChangeActionSkip, Kind: schemas.VariableKindTerraform, Name: "unchanged", Reasons: []string{"unchanged"}},
+		{Action: VariableChangeActionDelete, Kind: schemas.VariableKindTerraform, Name: "unmanaged"},
+		{Action: VariableChangeActionUpdate, Kind: schemas.VariableKindTerraform, Name: "updated", Value: "f********r", Reasons: []string{"value"}},
+	}, diff)
+
+	// Forcing the update also fetches the variables which are still valid
+	c.ProcessedVariables = map[string]schemas.VariableKind{}
+	diff, err = c.diffVariables(cfg, e, state, schemas.VariablesOwnershipAll, true)
+	assert.NoError(t, err)
+	assert.Equal(t, map[VariableChangeAction]int{
+		VariableChangeActionCreate: 1,
+		VariableChangeActionUpdate: 3,
+		VariableChangeActionDelete: 1,
+		VariableChangeActionSkip:   0,
+	}, diff.Count())
+}
+
+func TestDiffVariablesMappedNames(t *testing.T) {
+	cfg := &schemas.Config{}
+	assert.NoError(t, hclsimple.Decode("tfcw.hcl", []byte(`
+tfc {}
+
+envvar "aws" {
+  ttl = "1h"
+  vault {
+    path = "aws/creds/foo"
+    keys = {
+      access_key = "AWS_ACCESS_KEY_ID"
+      secret_key = "AWS_SECRET_ACCESS_KEY"
+    }
+  }
+}
+`), nil, cfg))
+
+	state := &schemas.State{
+		VariableExpirations: schemas.VariableExpirations{
+			schemas.VariableKindEnvironment: {
+				"aws": &schemas.VariableExpiration{TTL: time.Hour, ExpireAt: time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+		ManagedVariables:     schemas.ManagedVariables{},
+		VariableFingerprints: &schemas.VariableFingerprints{},
+	}
+
+	c := &Client{
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderVault: providerVault.NewPool(nil),
+		},
+		ProcessedVariables: map[string]schemas.VariableKind{},
+	}
+
+	// The variables mapped through 'keys' are skipped with the expiration of the configured variable
+	diff, err := c.diffVariables(cfg, TFCVariables{}, state, schemas.VariablesOwnershipAll, false)
+	assert.NoError(t, err)
+	assert.Equal(t, VariablesDiff{
+		{Action: VariableChangeActionSkip, Kind: schemas.VariableKindEnvironment, Name: "AWS_ACCESS_KEY_ID", Reasons: []string{"ttl valid until 2100-01-01T00:00:00Z"}},
+		{Action: VariableChangeActionSkip, Kind: schemas.VariableKindEnvironment, Name: "AWS_SECRET_ACCESS_KEY", Reasons: []string{"ttl valid until 2100-01-01T00:00:00Z"}},
+	}, diff)
+}
+
+func TestVariablesDiffString(t *testing.T) {
+	diff := VariablesDiff{
+		{Action: VariableChangeActionCreate, Kind: schemas.VariableKindTerraform, Name: "foo", Value: "b*r"},
+		{Action: VariableChangeActionUpdate, Kind: schemas.VariableKindTerraform, Name: "bar", Value: "b*z", Reasons: []string{"value", "hcl"}},
+		{Action: VariableChangeActionDelete, Kind: schemas.VariableKindEnvironment, Name: "BAZ"},
+		{Action: VariableChangeActionSkip, Kind: schemas.VariableKindEnvironment, Name: "QUX", Reasons: []string{"unchanged"}},
+	}
+
+	assert.Equal(t, `  + foo (terraform) : b*r
+  ~ bar (terraform) : b*z [value, hcl]
+  - BAZ (environment)
+  = QUX (environment) [unchanged]
+
+1 to create, 1 to update, 1 to delete, 1 to skip
+`, diff.String())
+
+	assert.Equal(t, "\n0 to create, 0 to update, 0 to delete, 0 to skip\n", VariablesDiff{}.String())
+}
+
+func TestGetVariablesNotToUpdate(t *testing.T) {
+	vars := schemas.Variables{
+		{Name: "foo", Kind: schemas.VariableKindTerraform},
+		{Name: "bar", Kind: schemas.VariableKindTerraform},
+	}
+
+	assert.Equal(t, schemas.Variables{vars[1]}, getVariablesNotToUpdate(vars, vars[:1]))
+	assert.Len(t, getVariablesNotToUpdate(vars, vars), 0)
+}
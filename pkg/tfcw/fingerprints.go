@@ -0,0 +1,109 @@
+package tfcw
+
+import (
+	"sort"
+
+	tfc "github.com/hashicorp/go-tfe"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+// variableDrift describes a variable which got altered outside of TFCW since it has been rendered
+type variableDrift struct {
+	Kind   schemas.VariableKind
+	Name   string
+	Reason string
+}
+
+// getVariablesDrift compares the variables of the workspace with the fingerprints of the values rendered by
+// TFCW. As sensitive values cannot be read from the TFC API, only their deletion or recreation can be detected
+func (c *Client) getVariablesDrift(cfg *schemas.Config, vars schemas.Variables, e TFCVariables, fingerprints *schemas.VariableFingerprints) (drifts []variableDrift) {
+	for _, v := range vars {
+		for _, variableName := range c.getVariableNames(v) {
+			fingerprint := fingerprints.Get(v.Kind, variableName)
+			if fingerprint == nil {
+				continue
+			}
+
+			if reason := getVariableDriftReason(cfg, v.Kind, variableName, e[getCategoryType(v.Kind)][variableName], fingerprint); len(reason) > 0 {
+				drifts = append(drifts, variableDrift{
+					Kind:   v.Kind,
+					Name:   variableName,
+					Reason: reason,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		if drifts[i].Kind != drifts[j].Kind {
+			return drifts[i].Kind < drifts[j].Kind
+		}
+		return drifts[i].Name < drifts[j].Name
+	})
+
+	return
+}
+
+func getVariableDriftReason(cfg *schemas.Config, kind schemas.VariableKind, name string, existingVariable *tfc.Variable, fingerprint *schemas.VariableFingerprint) string {
+	if existingVariable == nil {
+		return "deleted outside of TFCW"
+	}
+
+	if existingVariable.ID != fingerprint.ID {
+		return "recreated outside of TFCW"
+	}
+
+	if !existingVariable.Sensitive && len(fingerprint.Hash) > 0 {
+		if schemas.ComputeVariableFingerprint(cfg.State.GetFingerprintsKey(), kind, name, existingVariable.Value) != fingerprint.Hash {
+			return "value edited outside of TFCW"
+		}
+	}
+
+	return ""
+}
+
+// getFingerprintHash returns the fingerprint of the value of the variable. As their in-place edits from the TFC UI
+// cannot be detected, sensitive values are only fingerprinted when the skip of the unchanged sensitive variables
+// has been enabled, an empty hash is returned otherwise in order to get them written unconditionally
+func getFingerprintHash(cfg *schemas.Config, v *schemas.VariableWithValue) (string, error) {
+	if *v.Sensitive {
+		if skip, err := cfg.GetSkipUnchangedSensitiveVariables(); err != nil || !skip {
+			return "", err
+		}
+	}
+
+	return schemas.ComputeVariableFingerprint(cfg.State.GetFingerprintsKey(), v.Kind, v.Name, v.Value), nil
+}
+
+// isVariableUnchanged returns whether the variable on TFC already holds the value and the attributes
+// of the variable, given the hash of its value
+func isVariableUnchanged(v *schemas.VariableWithValue, existingVariable *tfc.Variable, fingerprint *schemas.VariableFingerprint, hash string) bool {
+	return existingVariable != nil && len(getVariableChanges(v, existingVariable, fingerprint, hash)) == 0
+}
+
+// getVariableChanges returns the fields of the variable which differ from the one currently set on TFC. Non-sensitive
+// values are compared directly whilst sensitive ones are compared using their fingerprints, they are considered as
+// changed when they have not been fingerprinted (empty hash)
+func getVariableChanges(v *schemas.VariableWithValue, existingVariable *tfc.Variable, fingerprint *schemas.VariableFingerprint, hash string) (changes []string) {
+	if existingVariable.Sensitive {
+		if len(hash) == 0 || fingerprint == nil || fingerprint.ID != existingVariable.ID || fingerprint.Hash != hash {
+			changes = append(changes, "value")
+		}
+	} else if existingVariable.Value != v.Value {
+		changes = append(changes, "value")
+	}
+
+	if existingVariable.Sensitive != *v.Sensitive {
+		changes = append(changes, "sensitive")
+	}
+
+	if existingVariable.HCL != *v.HCL {
+		changes = append(changes, "hcl")
+	}
+
+	if existingVariable.Description != *getDescription(v) {
+		changes = append(changes, "description")
+	}
+
+	return
+}
@@ -0,0 +1,164 @@
+package tfcw
+
+import (
+	"bytes"
+	"testing"
+
+	tfc "github.com/hashicorp/go-tfe"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFingerprintHash(t *testing.T) {
+	cfg := &schemas.Config{State: &schemas.StateStorage{FingerprintsKey: pointy.String("key")}}
+	v := &schemas.VariableWithValue{
+		Variable: schemas.Variable{
+			Name:      "foo",
+			Kind:      schemas.VariableKindTerraform,
+			Sensitive: pointy.Bool(false),
+		},
+		Value: "bar",
+	}
+
+	hash, err := getFingerprintHash(cfg, v)
+	assert.NoError(t, err)
+	assert.Equal(t, schemas.ComputeVariableFingerprint("key", schemas.VariableKindTerraform, "foo", "bar"), hash)
+
+	// Sensitive values are not fingerprinted unless their unchanged writes can be skipped
+	v.Sensitive = pointy.Bool(true)
+	hash, err = getFingerprintHash(cfg, v)
+	assert.NoError(t, err)
+	assert.Equal(t, "", hash)
+
+	cfg.TFC = &schemas.TFC{SkipUnchangedSensitiveVariables: pointy.Bool(true)}
+	hash, err = getFingerprintHash(cfg, v)
+	assert.NoError(t, err)
+	assert.Equal(t, schemas.ComputeVariableFingerprint("key", schemas.VariableKindTerraform, "foo", "bar"), hash)
+
+	cfg.State = nil
+	_, err = getFingerprintHash(cfg, v)
+	assert.EqualError(t, err, "a fingerprints-key has to be defined in the state block in order to skip unchanged sensitive variables")
+}
+
+func TestIsVariableUnchanged(t *testing.T) {
+	hash := schemas.ComputeVariableFingerprint("key", schemas.VariableKindTerraform, "foo", "bar")
+
+	v := &schemas.VariableWithValue{
+		Variable: schemas.Variable{
+			Name:      "foo",
+			Kind:      schemas.VariableKindTerraform,
+			Sensitive: pointy.Bool(true),
+			HCL:       pointy.Bool(false),
+		},
+		Value: "bar",
+	}
+	existingVariable := &tfc.Variable{ID: "var-foo", Key: "foo", Sensitive: true}
+	fingerprint := &schemas.VariableFingerprint{Hash: hash, ID: "var-foo"}
+
+	assert.True(t, isVariableUnchanged(v, existingVariable, fingerprint, hash))
+
+	// Unknown variables or values
+	assert.False(t, isVariableUnchanged(v, nil, fingerprint, hash))
+	assert.False(t, isVariableUnchanged(v, existingVariable, nil, hash))
+
+	// Sensitive values which have not been fingerprinted
+	assert.False(t, isVariableUnchanged(v, existingVariable, &schemas.VariableFingerprint{ID: "var-foo"}, ""))
+
+	// Updated value
+	updatedHash := schemas.ComputeVariableFingerprint("key", schemas.VariableKindTerraform, "foo", "baz")
+	assert.False(t, isVariableUnchanged(v, existingVariable, fingerprint, updatedHash))
+
+	// Recreated variable
+	assert.False(t, isVariableUnchanged(v, &tfc.Variable{ID: "var-bar", Key: "foo", Sensitive: true}, fingerprint, hash))
+
+	// Updated attributes
+	v.Description = pointy.String("foo")
+	assert.False(t, isVariableUnchanged(v, existingVariable, fingerprint, hash))
+	v.Description = nil
+
+	// Non-sensitive values edited outside of TFCW
+	v.Sensitive = pointy.Bool(false)
+	assert.False(t, isVariableUnchanged(v, &tfc.Variable{ID: "var-foo", Key: "foo", Value: "baz"}, fingerprint, hash))
+	assert.True(t, isVariableUnchanged(v, &tfc.Variable{ID: "var-foo", Key: "foo", Value: "bar"}, fingerprint, hash))
+}
+
+func TestGetVariablesDrift(t *testing.T) {
+	cfg := &schemas.Config{State: &schemas.StateStorage{FingerprintsKey: pointy.String("key")}}
+	fingerprints := &schemas.VariableFingerprints{}
+	for _, name := range []string{"deleted", "recreated", "edited", "sensitive", "unchanged"} {
+		fingerprints.Set(schemas.VariableKindTerraform, name, &schemas.VariableFingerprint{
+			Hash: schemas.ComputeVariableFingerprint("key", schemas.VariableKindTerraform, name, "bar"),
+			ID:   "var-" + name,
+		})
+	}
+
+	// Values which have not been fingerprinted cannot be compared
+	fingerprints.Set(schemas.VariableKindTerraform, "unfingerprinted", &schemas.VariableFingerprint{ID: "var-unfingerprinted"})
+
+	e := TFCVariables{
+		tfc.CategoryTerraform: {
+			"recreated": &tfc.Variable{ID: "var-foo", Value: "bar"},
+			"edited":    &tfc.Variable{ID: "var-edited", Value: "baz"},
+			"sensitive": &tfc.Variable{ID: "var-sensitive", Sensitive: true},
+			"unchanged": &tfc.Variable{ID: "var-unchanged", Value: "bar"},
+			"unknown":   &tfc.Variable{ID: "var-unknown", Value: "bar"},
+
+			"unfingerprinted": &tfc.Variable{ID: "var-unfingerprinted", Value: "baz"},
+		},
+	}
+
+	vars := schemas.Variables{}
+	for _, name := range []string{"unknown", "unchanged", "sensitive", "edited", "recreated", "deleted", "unfingerprinted"} {
+		vars = append(vars, &schemas.Variable{Name: name, Kind: schemas.VariableKindTerraform})
+	}
+
+	c := &Client{}
+	assert.Equal(t, []variableDrift{
+		{Kind: schemas.VariableKindTerraform, Name: "deleted", Reason: "deleted outside of TFCW"},
+		{Kind: schemas.VariableKindTerraform, Name: "edited", Reason: "value edited outside of TFCW"},
+		{Kind: schemas.VariableKindTerraform, Name: "recreated", Reason: "recreated outside of TFCW"},
+	}, c.getVariablesDrift(cfg, vars, e, fingerprints))
+}
+
+func TestRenderVariableOnTFCUnchanged(t *testing.T) {
+	var str bytes.Buffer
+	log.SetOutput(&str)
+	log.SetFormatter(&log.TextFormatter{DisableTimestamp: true})
+
+	fingerprints := &schemas.VariableFingerprints{}
+	hash := schemas.ComputeVariableFingerprint("key", schemas.VariableKindEnvironment, "foo", "bar")
+	fingerprints.Set(schemas.VariableKindEnvironment, "foo", &schemas.VariableFingerprint{Hash: hash, ID: "var-foo"})
+
+	e := TFCVariables{
+		tfc.CategoryEnv: {
+			"foo": &tfc.Variable{ID: "var-foo", Key: "foo", Sensitive: true},
+		},
+	}
+
+	v := &schemas.VariableWithValue{
+		Variable: schemas.Variable{Name: "foo", Kind: schemas.VariableKindEnvironment},
+		Value:    "bar",
+	}
+
+	// Sensitive values are written unconditionally by default
+	c := &Client{}
+	assert.NoError(t, c.renderVariableOnTFC(&schemas.Config{}, nil, v, e, fingerprints, true, false))
+	assert.Equal(t, "level=info msg=\"[DRY-RUN] Set variable 'foo' (environment) : **********\"\n", str.String())
+
+	// TFC is not called when the value has not changed and the skip is enabled
+	cfg := &schemas.Config{
+		TFC:   &schemas.TFC{SkipUnchangedSensitiveVariables: pointy.Bool(true)},
+		State: &schemas.StateStorage{FingerprintsKey: pointy.String("key")},
+	}
+
+	str.Reset()
+	assert.NoError(t, c.renderVariableOnTFC(cfg, nil, v, e, fingerprints, false, false))
+	assert.Equal(t, "level=info msg=\"Variable 'foo' (environment) is unchanged, skipping\"\n", str.String())
+
+	// Unless the update is forced
+	str.Reset()
+	assert.NoError(t, c.renderVariableOnTFC(cfg, nil, v, e, fingerprints, true, true))
+	assert.Equal(t, "level=info msg=\"[DRY-RUN] Set variable 'foo' (environment) : **********\"\n", str.String())
+}
@@ -0,0 +1,168 @@
+package tfcw
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tfc "github.com/hashicorp/go-tfe"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	log "github.com/sirupsen/logrus"
+)
+
+// RevokeVariableLeases revokes the leases of the values currently rendered on TFC and
+// expires the corresponding variables so that they get renewed on the next rendering
+func (c *Client) RevokeVariableLeases(cfg *schemas.Config, w *tfc.Workspace, dryRun bool) error {
+	store, state, err := c.loadState(cfg, w)
+	if err != nil {
+		return err
+	}
+	variableExpirations := state.VariableExpirations
+
+	hasChanges := false
+	for _, v := range cfg.GetVariables() {
+		expiration, ok := variableExpirations[v.Kind][v.Name]
+		if !ok || expiration.Lease == nil {
+			continue
+		}
+
+		if err = c.revokeLease(v, expiration.Lease, dryRun); err != nil {
+			return err
+		}
+
+		if !dryRun {
+			expiration.Lease = nil
+			expiration.ExpireAt = time.Now()
+			hasChanges = true
+		}
+	}
+
+	// The leases of the variables which have been removed from the config are revoked as well
+	removedChanges, err := c.revokeOrphanedLeases(cfg.GetVariables(), variableExpirations, dryRun)
+	if removedChanges {
+		hasChanges = true
+	}
+
+	if err != nil {
+		if hasChanges {
+			if saveErr := store.Save(state); saveErr != nil {
+				log.Errorf("unable to save the state into the %s : %s", store, saveErr)
+			}
+		}
+		return err
+	}
+
+	if !hasChanges {
+		log.Debug("no leases to revoke")
+		return nil
+	}
+
+	return store.Save(state)
+}
+
+// revokeRotatedLeases revokes the leases previously held by the variables which got
+// rotated, failures are only logged as the new values have already been rendered
+func (c *Client) revokeRotatedLeases(vars schemas.Variables, previousLeases, leases schemas.VariableLeases) {
+	for _, v := range vars {
+		previousLease := previousLeases.Get(v)
+		if previousLease == nil {
+			continue
+		}
+
+		if lease := leases.Get(v); lease != nil && lease.ID == previousLease.ID {
+			continue
+		}
+
+		if err := c.revokeLease(v, previousLease, false); err != nil {
+			log.Warnf("unable to revoke the previous lease of variable '%s' (%s) : %s", v.Name, v.Kind, err)
+		}
+	}
+}
+
+// revokeUnusedLeases revokes the leases obtained whilst fetching values which did
+// not get rendered
+func (c *Client) revokeUnusedLeases(vars schemas.Variables, leases schemas.VariableLeases) {
+	for _, v := range vars {
+		if lease := leases.Get(v); lease != nil {
+			if err := c.revokeLease(v, lease, false); err != nil {
+				log.Warnf("unable to revoke the unused lease of variable '%s' (%s) : %s", v.Name, v.Kind, err)
+			}
+		}
+	}
+}
+
+// revokeOrphanedLeases revokes the leases recorded within the expirations of the variables which are
+// not defined in the config anymore and drops their expirations. Their config being gone, the leases are
+// revoked using the provider which obtained them with its defaults
+func (c *Client) revokeOrphanedLeases(vars schemas.Variables, variableExpirations schemas.VariableExpirations, dryRun bool) (hasChanges bool, err error) {
+	definedVariables := map[schemas.VariableKind]map[string]bool{}
+	for _, v := range vars {
+		if _, ok := definedVariables[v.Kind]; !ok {
+			definedVariables[v.Kind] = map[string]bool{}
+		}
+		definedVariables[v.Kind][v.Name] = true
+	}
+
+	errors := []string{}
+	for kind, expirations := range variableExpirations {
+		for name, expiration := range expirations {
+			if definedVariables[kind][name] || expiration == nil || expiration.Lease == nil {
+				continue
+			}
+
+			if len(expiration.Lease.Provider) == 0 {
+				errors = append(errors, fmt.Sprintf("unknown provider for the lease '%s' of removed variable '%s' (%s)", expiration.Lease.ID, name, kind))
+				continue
+			}
+
+			v := &schemas.Variable{Name: name, Kind: kind}
+			if revokeErr := c.revokeLease(v, expiration.Lease, dryRun); revokeErr != nil {
+				errors = append(errors, revokeErr.Error())
+				continue
+			}
+
+			if !dryRun {
+				delete(expirations, name)
+				hasChanges = true
+			}
+		}
+
+		if len(expirations) == 0 {
+			delete(variableExpirations, kind)
+		}
+	}
+
+	if len(errors) > 0 {
+		sort.Strings(errors)
+		err = fmt.Errorf("%s", strings.Join(errors, ", "))
+	}
+	return
+}
+
+func (c *Client) revokeLease(v *schemas.Variable, lease *schemas.VariableLease, dryRun bool) error {
+	provider := lease.Provider
+	if len(provider) == 0 {
+		var err error
+		if provider, err = GetVariableProvider(v); err != nil {
+			return err
+		}
+	}
+
+	r, ok := c.Providers[provider].(LeaseRevoker)
+	if !ok {
+		return fmt.Errorf("provider '%s' does not support revoking leases (variable '%s')", provider, v.Name)
+	}
+
+	if dryRun {
+		log.Infof("[DRY-RUN] Revoke lease '%s' of variable '%s' (%s)", lease.ID, v.Name, v.Kind)
+		return nil
+	}
+
+	if err := r.RevokeLease(v, lease.ID); err != nil {
+		return err
+	}
+
+	log.Infof("Revoked lease '%s' of variable '%s' (%s)", lease.ID, v.Name, v.Kind)
+	return nil
+}
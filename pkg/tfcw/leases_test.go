@@ -0,0 +1,256 @@
+package tfcw
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tfc "github.com/hashicorp/go-tfe"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+type testLeaseRevoker struct {
+	testProvider
+	revokedLeases []string
+}
+
+func (p *testLeaseRevoker) RevokeLease(v *schemas.Variable, leaseID string) error {
+	if leaseID == "invalid" {
+		return fmt.Errorf("invalid lease")
+	}
+	p.revokedLeases = append(p.revokedLeases, leaseID)
+	return nil
+}
+
+// GetVariableValues returns the name of the variable as its value, leased under '<name>/new'
+func (p *testLeaseRevoker) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	return schemas.VariablesWithValues{
+		&schemas.VariableWithValue{
+			Variable: *v,
+			Value:    v.Name,
+			Lease:    &schemas.VariableLease{ID: v.Name + "/new"},
+		},
+	}, nil
+}
+
+func TestRevokeRotatedLeases(t *testing.T) {
+	p := &testLeaseRevoker{}
+	c := &Client{
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderVault: p,
+		},
+	}
+
+	vars := schemas.Variables{
+		{Name: "rotated", Kind: schemas.VariableKindEnvironment, Vault: &schemas.Vault{}},
+		{Name: "unchanged", Kind: schemas.VariableKindEnvironment, Vault: &schemas.Vault{}},
+		{Name: "new", Kind: schemas.VariableKindEnvironment, Vault: &schemas.Vault{}},
+		{Name: "invalid", Kind: schemas.VariableKindEnvironment, Vault: &schemas.Vault{}},
+	}
+
+	previousLeases := schemas.VariableLeases{}
+	previousLeases.Set(vars[0], &schemas.VariableLease{ID: "foo/1"})
+	previousLeases.Set(vars[1], &schemas.VariableLease{ID: "bar/1"})
+	previousLeases.Set(vars[3], &schemas.VariableLease{ID: "invalid"})
+
+	leases := schemas.VariableLeases{}
+	leases.Set(vars[0], &schemas.VariableLease{ID: "foo/2"})
+	leases.Set(vars[1], &schemas.VariableLease{ID: "bar/1"})
+	leases.Set(vars[2], &schemas.VariableLease{ID: "baz/1"})
+
+	c.revokeRotatedLeases(vars, previousLeases, leases)
+	assert.Equal(t, []string{"foo/1"}, p.revokedLeases)
+
+	p.revokedLeases = nil
+	c.revokeUnusedLeases(vars, leases)
+	assert.Equal(t, []string{"foo/2", "bar/1", "baz/1"}, p.revokedLeases)
+}
+
+func TestRevokeLeaseUnsupportedProvider(t *testing.T) {
+	c := &Client{
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderEnv: &testProvider{},
+		},
+	}
+
+	v := &schemas.Variable{Name: "foo", Env: &schemas.Env{}}
+	assert.EqualError(t, c.revokeLease(v, &schemas.VariableLease{ID: "foo"}, false), "provider 'env' does not support revoking leases (variable 'foo')")
+
+	// Nothing is attempted when running dry
+	p := &testLeaseRevoker{}
+	c.Providers[schemas.VariableProviderEnv] = p
+	assert.NoError(t, c.revokeLease(v, &schemas.VariableLease{ID: "foo"}, true))
+	assert.Len(t, p.revokedLeases, 0)
+}
+
+func TestRevokeOrphanedLeases(t *testing.T) {
+	p := &testLeaseRevoker{}
+	c := &Client{
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderVault: p,
+		},
+	}
+
+	vars := schemas.Variables{
+		{Name: "defined", Kind: schemas.VariableKindEnvironment, Vault: &schemas.Vault{}},
+	}
+
+	variableExpirations := func() schemas.VariableExpirations {
+		return schemas.VariableExpirations{
+			schemas.VariableKindEnvironment: {
+				"defined": {Lease: &schemas.VariableLease{ID: "defined/1", Provider: schemas.VariableProviderVault}},
+				"removed": {Lease: &schemas.VariableLease{ID: "removed/1", Provider: schemas.VariableProviderVault}},
+				"ttl":     {TTL: time.Hour},
+			},
+			schemas.VariableKindTerraform: {
+				"removed": {Lease: &schemas.VariableLease{ID: "removed/2", Provider: schemas.VariableProviderVault}},
+			},
+		}
+	}
+
+	// Nothing gets revoked nor dropped when running dry
+	e := variableExpirations()
+	hasChanges, err := c.revokeOrphanedLeases(vars, e, true)
+	assert.NoError(t, err)
+	assert.False(t, hasChanges)
+	assert.Len(t, p.revokedLeases, 0)
+	assert.Len(t, e[schemas.VariableKindTerraform], 1)
+
+	hasChanges, err = c.revokeOrphanedLeases(vars, e, false)
+	assert.NoError(t, err)
+	assert.True(t, hasChanges)
+	assert.ElementsMatch(t, []string{"removed/1", "removed/2"}, p.revokedLeases)
+	assert.Equal(t, schemas.VariableExpirations{
+		schemas.VariableKindEnvironment: {
+			"defined": {Lease: &schemas.VariableLease{ID: "defined/1", Provider: schemas.VariableProviderVault}},
+			"ttl":     {TTL: time.Hour},
+		},
+	}, e)
+
+	// Leases whose provider is unknown are kept
+	e = schemas.VariableExpirations{
+		schemas.VariableKindEnvironment: {
+			"removed": {Lease: &schemas.VariableLease{ID: "removed/1"}},
+		},
+	}
+	hasChanges, err = c.revokeOrphanedLeases(vars, e, false)
+	assert.EqualError(t, err, "unknown provider for the lease 'removed/1' of removed variable 'removed' (environment)")
+	assert.False(t, hasChanges)
+	assert.Len(t, e[schemas.VariableKindEnvironment], 1)
+}
+
+func TestRevokeVariableLeasesRemovedVariables(t *testing.T) {
+	p := &testLeaseRevoker{}
+	c := &Client{
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderVault: p,
+		},
+	}
+
+	cfg := newTestFileStateConfig(t)
+	cfg.EnvironmentVariables = schemas.Variables{
+		{Name: "defined", Vault: &schemas.Vault{}},
+	}
+
+	w := &tfc.Workspace{ID: "ws-test"}
+	store, err := c.GetStateStore(cfg, w, "")
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save(&schemas.State{
+		VariableExpirations: schemas.VariableExpirations{
+			schemas.VariableKindEnvironment: {
+				"defined": {ExpireAt: time.Now().Add(time.Hour), Lease: &schemas.VariableLease{ID: "defined/1", Provider: schemas.VariableProviderVault}},
+				"removed": {ExpireAt: time.Now().Add(time.Hour), Lease: &schemas.VariableLease{ID: "removed/1", Provider: schemas.VariableProviderVault}},
+			},
+		},
+	}))
+
+	assert.NoError(t, c.RevokeVariableLeases(cfg, w, false))
+	assert.ElementsMatch(t, []string{"defined/1", "removed/1"}, p.revokedLeases)
+
+	state, err := store.Load()
+	assert.NoError(t, err)
+	assert.Len(t, state.VariableExpirations[schemas.VariableKindEnvironment], 1)
+	assert.Nil(t, state.VariableExpirations[schemas.VariableKindEnvironment]["defined"].Lease)
+}
+
+func TestRenderVariablesOnTFCLeasesOnFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v2/ping":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v2/workspaces/ws-test/vars":
+			w.Header().Set("Content-Type", "application/vnd.api+json")
+			_, _ = w.Write([]byte(`{"data":[],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/workspaces/ws-test/vars":
+			body, _ := ioutil.ReadAll(r.Body)
+			if strings.Contains(string(body), `"key":"ko"`) {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/vnd.api+json")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"data":{"id":"var-ok","type":"vars","attributes":{"key":"ok","value":"ok","category":"env"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tfcClient, err := tfc.NewClient(&tfc.Config{Address: server.URL, Token: "_"})
+	assert.NoError(t, err)
+
+	p := &testLeaseRevoker{}
+	c := &Client{
+		TFC:     tfcClient,
+		Context: context.Background(),
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderVault: p,
+		},
+		ProcessedVariables: map[string]schemas.VariableKind{},
+	}
+
+	cfg := newTestFileStateConfig(t)
+	cfg.TFC = &schemas.TFC{}
+	cfg.EnvironmentVariables = schemas.Variables{
+		{Name: "ok", Vault: &schemas.Vault{}, TTL: pointy.String("1h")},
+		{Name: "ko", Vault: &schemas.Vault{}, TTL: pointy.String("1h")},
+	}
+
+	w := &tfc.Workspace{ID: "ws-test"}
+	store, err := c.GetStateStore(cfg, w, "")
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save(&schemas.State{
+		VariableExpirations: schemas.VariableExpirations{
+			schemas.VariableKindEnvironment: {
+				"ok":      {TTL: time.Hour, ExpireAt: time.Now().Add(time.Hour), Lease: &schemas.VariableLease{ID: "ok/1", Provider: schemas.VariableProviderVault}},
+				"ko":      {TTL: time.Hour, ExpireAt: time.Now().Add(time.Hour), Lease: &schemas.VariableLease{ID: "ko/1", Provider: schemas.VariableProviderVault}},
+				"removed": {Lease: &schemas.VariableLease{ID: "removed/1", Provider: schemas.VariableProviderVault}},
+			},
+		},
+	}))
+
+	assert.Error(t, c.renderVariablesOnTFC(cfg, w, false, true))
+
+	// The new lease of the variable which failed to be rendered is revoked, as well as the
+	// previous lease of the one which got rendered and the lease of the removed variable
+	assert.ElementsMatch(t, []string{"ko/new", "ok/1", "removed/1"}, p.revokedLeases)
+
+	state, err := store.Load()
+	assert.NoError(t, err)
+	expirations := state.VariableExpirations[schemas.VariableKindEnvironment]
+	assert.Len(t, expirations, 2)
+	assert.Equal(t, &schemas.VariableLease{ID: "ok/new", Provider: schemas.VariableProviderVault}, expirations["ok"].Lease)
+	assert.True(t, expirations["ok"].ExpireAt.After(time.Now()))
+
+	// The variable which failed keeps its previous lease and gets updated on the next run
+	assert.Equal(t, &schemas.VariableLease{ID: "ko/1", Provider: schemas.VariableProviderVault}, expirations["ko"].Lease)
+	assert.False(t, expirations["ko"].ExpireAt.After(time.Now()))
+	assert.Equal(t, schemas.ManagedVariables{schemas.VariableKindEnvironment: {"ok"}}, state.ManagedVariables)
+}
@@ -0,0 +1,197 @@
+package tfcw
+
+import (
+	"fmt"
+	"sync"
+
+	providerCommand "github.com/mvisonneau/tfcw/pkg/providers/command"
+	providerEnv "github.com/mvisonneau/tfcw/pkg/providers/env"
+	providerFile "github.com/mvisonneau/tfcw/pkg/providers/file"
+	providerPlugin "github.com/mvisonneau/tfcw/pkg/providers/plugin"
+	providerSOPS "github.com/mvisonneau/tfcw/pkg/providers/sops"
+	providerTemplate "github.com/mvisonneau/tfcw/pkg/providers/template"
+	providerValue "github.com/mvisonneau/tfcw/pkg/providers/value"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+// Provider is the interface implemented by the sources of variable values
+type Provider interface {
+	// GetVariableValues returns the value(s) of a variable, some providers
+	// can map several values out of a single variable definition
+	GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error)
+}
+
+// VariableNamesProvider can be implemented by providers which are able to
+// map values onto variables named differently than the one they are defined in
+type VariableNamesProvider interface {
+	// GetVariableNames returns the names of the variables which are going
+	// to be rendered out of the variable definition
+	GetVariableNames(v *schemas.Variable) []string
+}
+
+// LeaseRevoker can be implemented by providers returning values bound to a lease
+// (eg: dynamic secrets) which can be revoked once they are not used anymore
+type LeaseRevoker interface {
+	// RevokeLease revokes a lease obtained whilst fetching the values of the variable
+	RevokeLease(v *schemas.Variable, leaseID string) error
+}
+
+// VariablesValidator can be implemented by providers which are able to check the
+// variables they are configured for prior to fetching any value
+type VariablesValidator interface {
+	// ValidateVariables returns an error describing all the invalid variables
+	ValidateVariables(vars schemas.Variables) error
+}
+
+// ProviderFactory instantiates a Provider based on the tfcw configuration
+type ProviderFactory func(cfg *schemas.Config) (Provider, error)
+
+type providerRegistration struct {
+	isConfigured func(v *schemas.Variable) bool
+	factory      ProviderFactory
+}
+
+var (
+	registeredProvidersMutex sync.RWMutex
+	registeredProviders      = map[schemas.VariableProvider]providerRegistration{
+		schemas.VariableProviderEnv: {
+			isConfigured: func(v *schemas.Variable) bool { return v.Env != nil },
+			factory: func(_ *schemas.Config) (Provider, error) {
+				return &providerEnv.Client{}, nil
+			},
+		},
+		schemas.VariableProviderS5: {
+			isConfigured: func(v *schemas.Variable) bool { return v.S5 != nil },
+			factory: func(cfg *schemas.Config) (Provider, error) {
+				return GetS5Client(cfg), nil
+			},
+		},
+		schemas.VariableProviderVault: {
+			isConfigured: func(v *schemas.Variable) bool { return v.Vault != nil },
+			factory: func(cfg *schemas.Config) (Provider, error) {
+				return getVaultClient(cfg)
+			},
+		},
+		schemas.VariableProviderPlugin: {
+			isConfigured: func(v *schemas.Variable) bool { return v.Plugin != nil },
+			factory: func(cfg *schemas.Config) (Provider, error) {
+				return &providerPlugin.Client{WorkingDir: cfg.Runtime.WorkingDir}, nil
+			},
+		},
+		schemas.VariableProviderCommand: {
+			isConfigured: func(v *schemas.Variable) bool { return v.Command != nil },
+			factory: func(cfg *schemas.Config) (Provider, error) {
+				return &providerCommand.Client{WorkingDir: cfg.Runtime.WorkingDir}, nil
+			},
+		},
+		schemas.VariableProviderFile: {
+			isConfigured: func(v *schemas.Variable) bool { return v.File != nil },
+			factory: func(cfg *schemas.Config) (Provider, error) {
+				return &providerFile.Client{WorkingDir: cfg.Runtime.WorkingDir}, nil
+			},
+		},
+		schemas.VariableProviderSOPS: {
+			isConfigured: func(v *schemas.Variable) bool { return v.SOPS != nil },
+			factory: func(cfg *schemas.Config) (Provider, error) {
+				return &providerSOPS.Client{WorkingDir: cfg.Runtime.WorkingDir}, nil
+			},
+		},
+		schemas.VariableProviderValue: {
+			isConfigured: func(v *schemas.Variable) bool { return v.Literal != nil },
+			factory: func(_ *schemas.Config) (Provider, error) {
+				return &providerValue.Client{}, nil
+			},
+		},
+		schemas.VariableProviderTemplate: {
+			isConfigured: func(v *schemas.Variable) bool { return v.Template != nil },
+			factory: func(_ *schemas.Config) (Provider, error) {
+				// The resolver of the referenced variables is set once the client is created
+				return &providerTemplate.Client{}, nil
+			},
+		},
+	}
+)
+
+// RegisterProvider makes a provider available to the variables declaring a block
+// named after it. It can be used to plug in-house providers when embedding tfcw as a
+// library, their configuration can be fetched using schemas.Variable.DecodeProviderBlock
+func RegisterProvider(name schemas.VariableProvider, factory ProviderFactory) error {
+	if factory == nil {
+		return fmt.Errorf("a factory must be provided when registering provider '%s'", name)
+	}
+
+	registeredProvidersMutex.Lock()
+	defer registeredProvidersMutex.Unlock()
+
+	if _, exists := registeredProviders[name]; exists {
+		return fmt.Errorf("provider '%s' is already registered", name)
+	}
+
+	registeredProviders[name] = providerRegistration{
+		isConfigured: func(v *schemas.Variable) bool {
+			return v.HasProviderBlock(string(name))
+		},
+		factory: factory,
+	}
+
+	return nil
+}
+
+// GetVariableProvider returns the name of the provider configured for the variable
+func GetVariableProvider(v *schemas.Variable) (provider schemas.VariableProvider, err error) {
+	registeredProvidersMutex.RLock()
+	defer registeredProvidersMutex.RUnlock()
+
+	blockTypes := []string{}
+	configuredProviders := 0
+	for name, r := range registeredProviders {
+		blockTypes = append(blockTypes, string(name))
+		if r.isConfigured(v) {
+			configuredProviders++
+			provider = name
+		}
+	}
+
+	if err = v.ValidateProviderBlocks(blockTypes); err != nil {
+		return
+	}
+
+	if configuredProviders != 1 {
+		return "", fmt.Errorf("you can't have more or less than one provider configured per variable. Found %d for '%s'", configuredProviders, v.Name)
+	}
+
+	return
+}
+
+func newProvider(name schemas.VariableProvider, cfg *schemas.Config) (Provider, error) {
+	registeredProvidersMutex.RLock()
+	r, ok := registeredProviders[name]
+	registeredProvidersMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown provider '%s'", name)
+	}
+
+	return r.factory(cfg)
+}
+
+// getProviders instantiates the providers required by the variables of the config,
+// misconfigured variables are skipped here and reported when fetching their values
+func getProviders(cfg *schemas.Config) (providers map[schemas.VariableProvider]Provider, err error) {
+	providers = make(map[schemas.VariableProvider]Provider)
+	for _, v := range cfg.GetVariables() {
+		name, err := GetVariableProvider(v)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := providers[name]; ok {
+			continue
+		}
+
+		if providers[name], err = newProvider(name, cfg); err != nil {
+			return nil, fmt.Errorf("error getting %s client: %s", name, err)
+		}
+	}
+	return
+}
@@ -0,0 +1,174 @@
+package tfcw
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	providerEnv "github.com/mvisonneau/tfcw/pkg/providers/env"
+	providerS5 "github.com/mvisonneau/tfcw/pkg/providers/s5"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+type testProvider struct {
+	Value string `hcl:"value"`
+}
+
+func (p *testProvider) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	if err := v.DecodeProviderBlock("test", p); err != nil {
+		return nil, err
+	}
+
+	return schemas.VariablesWithValues{
+		&schemas.VariableWithValue{
+			Variable: *v,
+			Value:    p.Value,
+		},
+	}, nil
+}
+
+func TestGetVariableProviderEnv(t *testing.T) {
+	p, err := GetVariableProvider(&schemas.Variable{Env: &schemas.Env{}})
+	assert.NoError(t, err)
+	assert.Equal(t, schemas.VariableProviderEnv, p)
+}
+
+func TestGetVariableProviderS5(t *testing.T) {
+	p, err := GetVariableProvider(&schemas.Variable{S5: &schemas.S5{}})
+	assert.NoError(t, err)
+	assert.Equal(t, schemas.VariableProviderS5, p)
+}
+
+func TestGetVariableProviderVault(t *testing.T) {
+	p, err := GetVariableProvider(&schemas.Variable{Vault: &schemas.Vault{}})
+	assert.NoError(t, err)
+	assert.Equal(t, schemas.VariableProviderVault, p)
+}
+
+func TestGetVariableProviderInvalid(t *testing.T) {
+	p, err := GetVariableProvider(&schemas.Variable{Name: "foo"})
+	assert.Equal(t, fmt.Errorf("you can't have more or less than one provider configured per variable. Found 0 for 'foo'"), err)
+	assert.Equal(t, schemas.VariableProvider(""), p)
+
+	_, err = GetVariableProvider(&schemas.Variable{
+		Name: "foo",
+		Env:  &schemas.Env{},
+		S5:   &schemas.S5{},
+	})
+	assert.Equal(t, fmt.Errorf("you can't have more or less than one provider configured per variable. Found 2 for 'foo'"), err)
+}
+
+func TestRegisterProvider(t *testing.T) {
+	cfg := &schemas.Config{}
+	assert.NoError(t, hclsimple.Decode("tfcw.hcl", []byte(`
+tfvar "foo" {
+  test {
+    value = "bar"
+  }
+}
+
+tfvar "baz" {
+  unknown {}
+}
+`), nil, cfg))
+	variables := cfg.GetVariables()
+
+	// Not registered yet
+	_, err := GetVariableProvider(variables[0])
+	assert.Error(t, err)
+
+	assert.NoError(t, RegisterProvider("test", func(_ *schemas.Config) (Provider, error) {
+		return &testProvider{}, nil
+	}))
+	defer func() {
+		registeredProvidersMutex.Lock()
+		delete(registeredProviders, "test")
+		registeredProvidersMutex.Unlock()
+	}()
+
+	assert.Error(t, RegisterProvider("test", func(_ *schemas.Config) (Provider, error) { return nil, nil }))
+	assert.Error(t, RegisterProvider(schemas.VariableProviderEnv, func(_ *schemas.Config) (Provider, error) { return nil, nil }))
+	assert.Error(t, RegisterProvider("other", nil))
+
+	p, err := GetVariableProvider(variables[0])
+	assert.NoError(t, err)
+	assert.Equal(t, schemas.VariableProvider("test"), p)
+
+	// Unknown blocks are still rejected
+	_, err = GetVariableProvider(variables[1])
+	assert.Error(t, err)
+
+	providers, err := getProviders(&schemas.Config{TerraformVariables: schemas.Variables{variables[0]}})
+	assert.NoError(t, err)
+	assert.Len(t, providers, 1)
+
+	c := &Client{
+		Providers:          providers,
+		ProcessedVariables: map[string]schemas.VariableKind{},
+	}
+	values, err := c.fetchVariablesWithValues(cfg, variables[0])
+	assert.NoError(t, err)
+	assert.Len(t, values, 1)
+	assert.Equal(t, "bar", values[0].Value)
+}
+
+func TestGetProviders(t *testing.T) {
+	// No variables, no providers
+	providers, err := getProviders(&schemas.Config{})
+	assert.NoError(t, err)
+	assert.Len(t, providers, 0)
+
+	// Misconfigured variables are ignored
+	providers, err = getProviders(&schemas.Config{
+		TerraformVariables: schemas.Variables{
+			&schemas.Variable{Name: "foo"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, providers, 0)
+
+	s5CipherEngineType := schemas.S5CipherEngineTypeAES
+	providers, err = getProviders(&schemas.Config{
+		EnvironmentVariables: schemas.Variables{
+			&schemas.Variable{Env: &schemas.Env{}},
+			&schemas.Variable{Env: &schemas.Env{}},
+			&schemas.Variable{
+				S5: &schemas.S5{
+					CipherEngineType: &s5CipherEngineType,
+				},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, providers, 2)
+	assert.IsType(t, &providerEnv.Client{}, providers[schemas.VariableProviderEnv])
+	assert.IsType(t, &providerS5.Client{}, providers[schemas.VariableProviderS5])
+}
+
+func TestValidateVariables(t *testing.T) {
+	cfg := &schemas.Config{
+		EnvironmentVariables: schemas.Variables{
+			{Name: "foo", Env: &schemas.Env{Variable: "TFCW_TEST_UNSET", Required: pointy.Bool(true)}},
+		},
+	}
+
+	c := &Client{
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderEnv: &providerEnv.Client{},
+		},
+	}
+
+	os.Unsetenv("TFCW_TEST_UNSET")
+	assert.EqualError(t, c.ValidateVariables(cfg), "missing required environment variables: TFCW_TEST_UNSET (variable 'foo')")
+
+	os.Setenv("TFCW_TEST_UNSET", "bar")
+	assert.NoError(t, c.ValidateVariables(cfg))
+	os.Unsetenv("TFCW_TEST_UNSET")
+
+	// Misconfigured variables
+	cfg.EnvironmentVariables[0].Env = nil
+	assert.Error(t, c.ValidateVariables(cfg))
+}
@@ -0,0 +1,328 @@
+package tfcw
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tfc "github.com/hashicorp/go-tfe"
+	providerFile "github.com/mvisonneau/tfcw/pkg/providers/file"
+	providerVault "github.com/mvisonneau/tfcw/pkg/providers/vault"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	stateFile "github.com/mvisonneau/tfcw/pkg/state/file"
+	stateS3 "github.com/mvisonneau/tfcw/pkg/state/s3"
+	stateVault "github.com/mvisonneau/tfcw/pkg/state/vault"
+	log "github.com/sirupsen/logrus"
+)
+
+// StateStore is the interface implemented by the backends TFCW can keep its state in
+type StateStore interface {
+	// Load returns the stored state, an empty one if it has not been stored yet
+	Load() (*schemas.State, error)
+
+	// Save stores the state, replacing the previous one
+	Save(state *schemas.State) error
+
+	// Delete removes the stored state
+	Delete() error
+
+	// String returns a description of where the state is stored
+	String() string
+}
+
+// workspaceStateStore keeps the state of TFCW within environment variables of the workspace
+type workspaceStateStore struct {
+	c *Client
+	w *tfc.Workspace
+}
+
+// Load returns the state stored within the environment variables of the workspace
+func (s *workspaceStateStore) Load() (state *schemas.State, err error) {
+	_, internal, err := s.c.listVariables(s.w)
+	if err != nil {
+		return nil, fmt.Errorf("terraform cloud: %s", err)
+	}
+
+	state = &schemas.State{}
+	if state.VariableExpirations, err = internal.getVariableExpirations(); err != nil {
+		return nil, fmt.Errorf("terraform cloud: %s", err)
+	}
+
+	if state.ManagedVariables, err = internal.getManagedVariables(); err != nil {
+		return nil, fmt.Errorf("terraform cloud: %s", err)
+	}
+
+	if state.VariableFingerprints, err = internal.getVariableFingerprints(); err != nil {
+		return nil, fmt.Errorf("terraform cloud: %s", err)
+	}
+
+	return
+}
+
+// Save writes the state within the environment variables of the workspace
+func (s *workspaceStateStore) Save(state *schemas.State) error {
+	_, internal, err := s.c.listVariables(s.w)
+	if err != nil {
+		return fmt.Errorf("terraform cloud: %s", err)
+	}
+
+	if err = s.c.updateInternalVariable(s.w, VariableExpirationsName, state.VariableExpirations, len(state.VariableExpirations) == 0, internal[VariableExpirationsName]); err != nil {
+		return err
+	}
+
+	if err = s.c.updateInternalVariable(s.w, ManagedVariablesName, state.ManagedVariables, len(state.ManagedVariables) == 0, internal[ManagedVariablesName]); err != nil {
+		return err
+	}
+
+	return s.c.updateInternalVariable(s.w, VariableFingerprintsName, state.VariableFingerprints, state.VariableFingerprints.IsEmpty(), internal[VariableFingerprintsName])
+}
+
+// Delete removes the environment variables holding the state from the workspace
+func (s *workspaceStateStore) Delete() error {
+	_, internal, err := s.c.listVariables(s.w)
+	if err != nil {
+		return fmt.Errorf("terraform cloud: %s", err)
+	}
+
+	for _, v := range internal {
+		if err = s.c.TFC.Variables.Delete(s.c.Context, s.w.ID, v.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String returns a description of the store
+func (s *workspaceStateStore) String() string {
+	return fmt.Sprintf("workspace '%s' environment variables", s.w.Name)
+}
+
+// workspaceBoundStateStore records the ID of the workspace within the state it saves and refuses to load
+// the state of another workspace, the location of the state being configurable and therefore shareable
+type workspaceBoundStateStore struct {
+	StateStore
+	w *tfc.Workspace
+}
+
+// Load returns the stored state, an error if it belongs to another workspace
+func (s *workspaceBoundStateStore) Load() (*schemas.State, error) {
+	state, err := s.StateStore.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(state.WorkspaceID) > 0 && state.WorkspaceID != s.w.ID {
+		return nil, fmt.Errorf("the state belongs to the workspace '%s' and not to '%s' (%s), its location needs to be specific to each workspace (eg: using the %s placeholder)", state.WorkspaceID, s.w.Name, s.w.ID, schemas.StateLocationWorkspacePlaceholder)
+	}
+
+	return state, nil
+}
+
+// Save stores the state alongside the ID of the workspace
+func (s *workspaceBoundStateStore) Save(state *schemas.State) error {
+	state.WorkspaceID = s.w.ID
+	return s.StateStore.Save(state)
+}
+
+// getStateLocation replaces the placeholders of the location of the state with the
+// organization and the name of the workspace
+func getStateLocation(cfg *schemas.Config, w *tfc.Workspace, location string) string {
+	return strings.NewReplacer(
+		schemas.StateLocationOrganizationPlaceholder, cfg.Runtime.TFC.Organization,
+		schemas.StateLocationWorkspacePlaceholder, w.Name,
+	).Replace(location)
+}
+
+// GetStateStore returns the store of the state of the workspace, using the configured backend
+// unless another one is specified
+func (c *Client) GetStateStore(cfg *schemas.Config, w *tfc.Workspace, backend schemas.StateBackend) (StateStore, error) {
+	if backend == "" {
+		var err error
+		if backend, err = cfg.State.GetBackend(); err != nil {
+			return nil, err
+		}
+	}
+
+	if backend == schemas.StateBackendWorkspace {
+		return &workspaceStateStore{c: c, w: w}, nil
+	}
+
+	store, err := c.getExternalStateStore(cfg, w, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &workspaceBoundStateStore{StateStore: store, w: w}, nil
+}
+
+// getExternalStateStore returns the store of the state of the workspace for the backends
+// storing it outside of the workspace
+func (c *Client) getExternalStateStore(cfg *schemas.Config, w *tfc.Workspace, backend schemas.StateBackend) (StateStore, error) {
+	switch backend {
+	case schemas.StateBackendFile:
+		path := schemas.DefaultStateFilePath
+		if cfg.State != nil && cfg.State.File != nil && cfg.State.File.Path != nil {
+			path = *cfg.State.File.Path
+		}
+
+		path, err := providerFile.GetPath(cfg.Runtime.WorkingDir, getStateLocation(cfg, w, path))
+		if err != nil {
+			return nil, err
+		}
+		return &stateFile.Store{Path: path}, nil
+	case schemas.StateBackendVault:
+		if cfg.State == nil || cfg.State.Vault == nil || len(cfg.State.Vault.Path) == 0 {
+			return nil, fmt.Errorf("a 'vault' block with a path is required in order to use the vault state backend")
+		}
+
+		pool, ok := c.Providers[schemas.VariableProviderVault].(*providerVault.Pool)
+		if !ok {
+			var err error
+			if pool, err = getVaultClient(cfg); err != nil {
+				return nil, err
+			}
+		}
+
+		client, err := pool.GetClient(cfg.State.Vault.GetVault())
+		if err != nil {
+			return nil, err
+		}
+
+		// The client only gets logged in within the namespace when an auth block is defined
+		if cfg.State.Vault.Namespace != nil {
+			if client, err = client.WithNamespace(*cfg.State.Vault.Namespace); err != nil {
+				return nil, err
+			}
+		}
+
+		return &stateVault.Store{
+			Client: client,
+			Mount:  cfg.State.Vault.Mount,
+			Path:   getStateLocation(cfg, w, cfg.State.Vault.Path),
+		}, nil
+	case schemas.StateBackendS3:
+		if cfg.State == nil || cfg.State.S3 == nil || len(cfg.State.S3.Bucket) == 0 || len(cfg.State.S3.Key) == 0 {
+			return nil, fmt.Errorf("an 's3' block with a bucket and a key is required in order to use the s3 state backend")
+		}
+
+		s3 := *cfg.State.S3
+		s3.Key = getStateLocation(cfg, w, s3.Key)
+		return stateS3.NewStore(&s3)
+	}
+
+	return nil, fmt.Errorf("unsupported state backend '%s'", backend)
+}
+
+// loadState returns the store of the state of the workspace alongside the state it holds
+func (c *Client) loadState(cfg *schemas.Config, w *tfc.Workspace) (StateStore, *schemas.State, error) {
+	store, err := c.GetStateStore(cfg, w, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	state, err := store.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to load the state from the %s : %s", store, err)
+	}
+
+	if state.ManagedVariables == nil {
+		state.ManagedVariables = schemas.ManagedVariables{}
+	}
+
+	if state.VariableFingerprints == nil {
+		state.VariableFingerprints = &schemas.VariableFingerprints{}
+	}
+
+	return store, state, nil
+}
+
+// MigrateState moves the state of the workspace from a backend to another one, the destination
+// defaults to the configured backend
+func (c *Client) MigrateState(cfg *schemas.Config, w *tfc.Workspace, from, to schemas.StateBackend, dryRun bool) error {
+	source, err := c.GetStateStore(cfg, w, from)
+	if err != nil {
+		return err
+	}
+
+	destination, err := c.GetStateStore(cfg, w, to)
+	if err != nil {
+		return err
+	}
+
+	if source.String() == destination.String() {
+		return fmt.Errorf("the source and the destination of the migration cannot be the same (%s)", source)
+	}
+
+	state, err := source.Load()
+	if err != nil {
+		return fmt.Errorf("unable to load the state from the %s : %s", source, err)
+	}
+
+	// We do not want to override any state which would already be in use
+	existingState, err := destination.Load()
+	if err != nil {
+		return fmt.Errorf("unable to load the state from the %s : %s", destination, err)
+	}
+
+	if !existingState.IsEmpty() {
+		return fmt.Errorf("a state is already stored within the %s, it needs to be removed prior to migrating", destination)
+	}
+
+	if dryRun {
+		log.Infof("[DRY-RUN] Migrate the state from the %s to the %s", source, destination)
+		return nil
+	}
+
+	if err = destination.Save(state); err != nil {
+		return fmt.Errorf("unable to save the state into the %s : %s", destination, err)
+	}
+
+	if err = source.Delete(); err != nil {
+		return fmt.Errorf("unable to delete the state from the %s : %s", source, err)
+	}
+
+	log.Infof("Migrated the state from the %s to the %s", source, destination)
+	return nil
+}
+
+// updateInternalVariable stores the value as JSON within the internal variable, which gets deleted when empty
+func (c *Client) updateInternalVariable(w *tfc.Workspace, name string, value interface{}, empty bool, existingVariable *tfc.Variable) error {
+	valueByte, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	if empty {
+		if existingVariable != nil {
+			log.Debugf("deleting %s on TFC", name)
+			return c.TFC.Variables.Delete(c.Context, w.ID, existingVariable.ID)
+		}
+		log.Debugf("empty %s and already unset on TFC", name)
+		return nil
+	}
+
+	if existingVariable != nil {
+		if existingVariable.Value == string(valueByte) {
+			log.Debugf("%s is already up to date on TFC", name)
+			return nil
+		}
+
+		log.Debugf("updating %s on TFC", name)
+		_, err = c.TFC.Variables.Update(c.Context, w.ID, existingVariable.ID, tfc.VariableUpdateOptions{
+			Value: tfc.String(string(valueByte)),
+		})
+		return err
+	}
+
+	log.Debugf("creating %s on TFC", name)
+	category := tfc.CategoryEnv
+	_, err = c.TFC.Variables.Create(c.Context, w.ID, tfc.VariableCreateOptions{
+		Key:       tfc.String(name),
+		Value:     tfc.String(string(valueByte)),
+		Category:  &category,
+		Sensitive: tfc.Bool(false),
+		HCL:       tfc.Bool(false),
+	})
+
+	return err
+}
@@ -0,0 +1,183 @@
+package tfcw
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	tfc "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	stateFile "github.com/mvisonneau/tfcw/pkg/state/file"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStateStore(t *testing.T) {
+	c := &Client{}
+	w := &tfc.Workspace{ID: "ws-foo", Name: "foo"}
+	cfg := &schemas.Config{Runtime: schemas.Runtime{WorkingDir: "/foo"}}
+	cfg.Runtime.TFC.Organization = "acme"
+
+	s, err := c.GetStateStore(cfg, w, "")
+	assert.NoError(t, err)
+	assert.IsType(t, &workspaceStateStore{}, s)
+
+	s, err = c.GetStateStore(cfg, w, schemas.StateBackendFile)
+	assert.NoError(t, err)
+	assert.Equal(t, &workspaceBoundStateStore{StateStore: &stateFile.Store{Path: "/foo/.tfcw/acme/foo.state.json"}, w: w}, s)
+
+	_, err = c.GetStateStore(cfg, w, schemas.StateBackendVault)
+	assert.EqualError(t, err, "a 'vault' block with a path is required in order to use the vault state backend")
+
+	_, err = c.GetStateStore(cfg, w, schemas.StateBackendS3)
+	assert.EqualError(t, err, "an 's3' block with a bucket and a key is required in order to use the s3 state backend")
+
+	assert.NoError(t, hclsimple.Decode("tfcw.hcl", []byte(`
+state {
+  backend = "vault"
+
+  file {
+    path = "state.json"
+  }
+
+  vault {
+    address = "http://localhost:8200"
+    token   = "foo"
+    mount   = "kv"
+    path    = "tfcw/{organization}/{workspace}"
+  }
+
+  s3 {
+    bucket = "tfcw"
+    key    = "{workspace}.json"
+    region = "eu-west-1"
+  }
+}
+`), nil, cfg))
+
+	s, err = c.GetStateStore(cfg, w, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "vault secret 'kv/data/tfcw/acme/foo'", s.String())
+
+	s, err = c.GetStateStore(cfg, w, schemas.StateBackendFile)
+	assert.NoError(t, err)
+	assert.Equal(t, &workspaceBoundStateStore{StateStore: &stateFile.Store{Path: "/foo/state.json"}, w: w}, s)
+
+	s, err = c.GetStateStore(cfg, w, schemas.StateBackendS3)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3 object 's3://tfcw/foo.json'", s.String())
+}
+
+func TestWorkspaceBoundStateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	foo := &workspaceBoundStateStore{StateStore: &stateFile.Store{Path: path}, w: &tfc.Workspace{ID: "ws-foo", Name: "foo"}}
+	bar := &workspaceBoundStateStore{StateStore: &stateFile.Store{Path: path}, w: &tfc.Workspace{ID: "ws-bar", Name: "bar"}}
+
+	// States which have not been bound to any workspace yet can be loaded
+	assert.NoError(t, foo.StateStore.Save(&schemas.State{ManagedVariables: schemas.ManagedVariables{schemas.VariableKindTerraform: {"foo"}}}))
+	_, err := bar.Load()
+	assert.NoError(t, err)
+
+	assert.NoError(t, foo.Save(&schemas.State{ManagedVariables: schemas.ManagedVariables{schemas.VariableKindTerraform: {"foo"}}}))
+	state, err := foo.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "ws-foo", state.WorkspaceID)
+
+	_, err = bar.Load()
+	assert.EqualError(t, err, "the state belongs to the workspace 'ws-foo' and not to 'bar' (ws-bar), its location needs to be specific to each workspace (eg: using the {workspace} placeholder)")
+}
+
+func TestMigrateState(t *testing.T) {
+	secrets := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			secret, ok := secrets[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"errors":[]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":` + string(secret) + `}`))
+		case http.MethodPut:
+			secrets[r.URL.Path], _ = ioutil.ReadAll(r.Body)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := &schemas.Config{
+		Runtime: schemas.Runtime{WorkingDir: dir},
+		State: &schemas.StateStorage{
+			Vault: &schemas.StateVault{
+				Address: &server.URL,
+				Token:   &dir,
+				Path:    "secret/tfcw/foo",
+			},
+		},
+	}
+	cfg.Runtime.TFC.Organization = "acme"
+	c := &Client{}
+	w := &tfc.Workspace{ID: "ws-foo", Name: "foo"}
+
+	state := &schemas.State{ManagedVariables: schemas.ManagedVariables{schemas.VariableKindTerraform: {"foo"}}}
+	source := &stateFile.Store{Path: filepath.Join(dir, ".tfcw", "acme", "foo.state.json")}
+	assert.NoError(t, source.Save(state))
+
+	assert.EqualError(t, c.MigrateState(cfg, w, schemas.StateBackendFile, schemas.StateBackendFile, false), "the source and the destination of the migration cannot be the same (file '"+source.Path+"')")
+
+	// Nothing happens whilst running dry
+	assert.NoError(t, c.MigrateState(cfg, w, schemas.StateBackendFile, schemas.StateBackendVault, true))
+	assert.Len(t, secrets, 0)
+
+	assert.NoError(t, c.MigrateState(cfg, w, schemas.StateBackendFile, schemas.StateBackendVault, false))
+	data := map[string]string{}
+	assert.NoError(t, json.Unmarshal(secrets["/v1/secret/tfcw/foo"], &data))
+	assert.Equal(t, `{"workspace_id":"ws-foo","managed_variables":{"terraform":["foo"]}}`, data["state"])
+
+	// The source is removed once migrated
+	migratedState, err := source.Load()
+	assert.NoError(t, err)
+	assert.True(t, migratedState.IsEmpty())
+
+	// Existing states are not overridden
+	assert.NoError(t, source.Save(state))
+	assert.EqualError(t, c.MigrateState(cfg, w, schemas.StateBackendFile, schemas.StateBackendVault, false), "a state is already stored within the vault secret 'secret/tfcw/foo', it needs to be removed prior to migrating")
+}
+
+func TestGetStateStoreVaultNamespace(t *testing.T) {
+	namespaces := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespaces = append(namespaces, r.Method+" "+r.Header.Get("X-Vault-Namespace"))
+		if r.Method == http.MethodGet {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := &schemas.Config{
+		State: &schemas.StateStorage{
+			Vault: &schemas.StateVault{
+				Address:   &server.URL,
+				Token:     pointy.String("foo"),
+				Namespace: pointy.String("team-a"),
+				Path:      "secret/tfcw/foo",
+			},
+		},
+	}
+	c := &Client{}
+	w := &tfc.Workspace{ID: "ws-foo", Name: "foo"}
+
+	s, err := c.GetStateStore(cfg, w, schemas.StateBackendVault)
+	assert.NoError(t, err)
+
+	_, err = s.Load()
+	assert.NoError(t, err)
+	assert.NoError(t, s.Save(&schemas.State{}))
+	assert.Equal(t, []string{"GET team-a", "PUT team-a"}, namespaces)
+}
@@ -4,11 +4,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	tfc "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	providerTemplate "github.com/mvisonneau/tfcw/pkg/providers/template"
 	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/mvisonneau/tfcw/pkg/transforms"
+	"github.com/mvisonneau/tfcw/pkg/validations"
 	log "github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
 )
 
 // TFCVariables gives us an accessible fashion for managing all our
@@ -27,21 +35,110 @@ const (
 
 	// VariableExpirationsName is the name of the variable used for storing VariableExpirations in TFC
 	VariableExpirationsName string = "__TFCW_VARIABLES_EXPIRATIONS"
+
+	// ManagedVariablesName is the name of the variable used for storing ManagedVariables in TFC
+	ManagedVariablesName string = "__TFCW_MANAGED_VARIABLES"
+
+	// VariableFingerprintsName is the name of the variable used for storing VariableFingerprints in TFC
+	VariableFingerprintsName string = "__TFCW_VARIABLES_FINGERPRINTS"
 )
 
-// RenderVariablesOnTFC issues a rendering of all variables defined in a schemas.Config object on TFC
+// isInternalVariable returns whether the variable is used by TFCW in order to store its state
+func isInternalVariable(key string) bool {
+	return key == VariableExpirationsName || key == ManagedVariablesName || key == VariableFingerprintsName
+}
+
+// internalVariables holds the variables used by TFCW in order to store its own state
+// onto the workspace, indexed by their names
+type internalVariables map[string]*tfc.Variable
+
+// getVariableExpirations parses the expirations of the variables currently set on TFC
+func (i internalVariables) getVariableExpirations() (variableExpirations schemas.VariableExpirations, err error) {
+	if v, ok := i[VariableExpirationsName]; ok {
+		if err = json.Unmarshal([]byte(v.Value), &variableExpirations); err != nil {
+			// TODO: Remove the existing variable automatically?
+			err = fmt.Errorf("unable to parse the variable ttls currently set on TFC (%s) : %s", VariableExpirationsName, err.Error())
+		}
+	}
+	return
+}
+
+// getVariableFingerprints parses the fingerprints of the values currently set on TFC
+func (i internalVariables) getVariableFingerprints() (variableFingerprints *schemas.VariableFingerprints, err error) {
+	variableFingerprints = &schemas.VariableFingerprints{}
+	if v, ok := i[VariableFingerprintsName]; ok {
+		if err = json.Unmarshal([]byte(v.Value), variableFingerprints); err != nil {
+			err = fmt.Errorf("unable to parse the variable fingerprints currently set on TFC (%s) : %s", VariableFingerprintsName, err.Error())
+		}
+	}
+	return
+}
+
+// getManagedVariables parses the registry of the variables created by TFCW on the workspace
+func (i internalVariables) getManagedVariables() (managedVariables schemas.ManagedVariables, err error) {
+	managedVariables = schemas.ManagedVariables{}
+	if v, ok := i[ManagedVariablesName]; ok {
+		if err = json.Unmarshal([]byte(v.Value), &managedVariables); err != nil {
+			err = fmt.Errorf("unable to parse the managed variables currently set on TFC (%s) : %s", ManagedVariablesName, err.Error())
+		}
+	}
+	return
+}
+
+// RenderVariablesOnTFC issues a rendering of all variables defined in a schemas.Config object on TFC,
+// they are expected to have been checked beforehand using ValidateVariables
 func (c *Client) RenderVariablesOnTFC(cfg *schemas.Config, w *tfc.Workspace, dryRun, forceUpdate bool) error {
 	log.Info("Processing variables and updating their values on TFC")
 	return c.renderVariablesOnTFC(cfg, w, dryRun, forceUpdate)
 }
 
-// RenderVariablesLocally issues a rendering of all variables defined in a schemas.Config object on TFC
+// RenderVariablesLocally issues a rendering of all variables defined in a schemas.Config object on TFC,
+// they are expected to have been checked beforehand using ValidateVariables
 func (c *Client) RenderVariablesLocally(cfg *schemas.Config) error {
 	log.Info("Processing variables and updating their values locally")
-	return c.renderVariablesLocally(cfg.GetVariables())
+	return c.renderVariablesLocally(cfg, cfg.GetVariables())
+}
+
+// ValidateVariables checks the variables using their providers before fetching any value,
+// it can be used to fail fast prior to interacting with TFC
+func (c *Client) ValidateVariables(cfg *schemas.Config) error {
+	variablesByProvider := map[schemas.VariableProvider]schemas.Variables{}
+	for _, v := range cfg.GetVariables() {
+		provider, err := GetVariableProvider(v)
+		if err != nil {
+			return err
+		}
+
+		if err = transforms.Validate(v.Transforms); err != nil {
+			return fmt.Errorf("invalid configuration for variable '%s': %s", v.Name, err)
+		}
+
+		if err = validations.Check(v.Validations); err != nil {
+			return fmt.Errorf("invalid configuration for variable '%s': %s", v.Name, err)
+		}
+		variablesByProvider[provider] = append(variablesByProvider[provider], v)
+	}
+
+	providers := []string{}
+	for provider := range variablesByProvider {
+		providers = append(providers, string(provider))
+	}
+	sort.Strings(providers)
+
+	for _, provider := range providers {
+		if p, ok := c.Providers[schemas.VariableProvider(provider)].(VariablesValidator); ok {
+			if err := p.ValidateVariables(variablesByProvider[schemas.VariableProvider(provider)]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
-func (c *Client) setVariableOnTFC(cfg *schemas.Config, w *tfc.Workspace, v *schemas.VariableWithValue, e TFCVariables) (*tfc.Variable, error) {
+// setVariableDefaults sets the attributes of the variable which are not explicitly defined
+// using the defaults of the config
+func setVariableDefaults(cfg *schemas.Config, v *schemas.VariableWithValue) {
 	if v.Sensitive == nil {
 		if cfg.Defaults == nil || cfg.Defaults.Variable == nil || cfg.Defaults.Variable.Sensitive == nil {
 			v.Sensitive = tfc.Bool(true)
@@ -51,19 +148,28 @@ func (c *Client) setVariableOnTFC(cfg *schemas.Config, w *tfc.Workspace, v *sche
 	}
 
 	if v.HCL == nil {
-		if cfg.Defaults == nil || cfg.Defaults.Variable == nil || cfg.Defaults.Variable.HCL == nil {
+		if v.HCL = cfg.GetVariableHCL(&v.Variable); v.HCL == nil {
 			v.HCL = tfc.Bool(false)
-		} else {
-			v.HCL = cfg.Defaults.Variable.HCL
 		}
 	}
 
+	if v.Description == nil && cfg.Defaults != nil && cfg.Defaults.Variable != nil {
+		v.Description = cfg.Defaults.Variable.Description
+	}
+
+	if v.Category == nil && cfg.Defaults != nil && cfg.Defaults.Variable != nil {
+		v.Category = cfg.Defaults.Variable.Category
+	}
+}
+
+func (c *Client) setVariableOnTFC(w *tfc.Workspace, v *schemas.VariableWithValue, e TFCVariables) (*tfc.Variable, error) {
 	if existingVariable, ok := e[getCategoryType(v.Kind)][v.Name]; ok {
 		updatedVariable, err := c.TFC.Variables.Update(c.Context, w.ID, existingVariable.ID, tfc.VariableUpdateOptions{
-			Key:       &v.Name,
-			Value:     &v.Value,
-			Sensitive: v.Sensitive,
-			HCL:       v.HCL,
+			Key:         &v.Name,
+			Value:       &v.Value,
+			Description: getDescription(v),
+			Sensitive:   v.Sensitive,
+			HCL:         v.HCL,
 		})
 
 		// In case we cannot update the fields, we delete the variable and recreate it
@@ -79,51 +185,92 @@ func (c *Client) setVariableOnTFC(cfg *schemas.Config, w *tfc.Workspace, v *sche
 	}
 
 	return c.TFC.Variables.Create(c.Context, w.ID, tfc.VariableCreateOptions{
-		Key:       &v.Name,
-		Value:     &v.Value,
-		Category:  tfc.Category(getCategoryType(v.Kind)),
-		Sensitive: v.Sensitive,
-		HCL:       v.HCL,
+		Key:         &v.Name,
+		Value:       &v.Value,
+		Description: getDescription(v),
+		Category:    tfc.Category(getCategoryType(v.Kind)),
+		Sensitive:   v.Sensitive,
+		HCL:         v.HCL,
 	})
 }
 
-func (c *Client) purgeUnmanagedVariables(vars schemas.Variables, e TFCVariables, dryRun bool) error {
+// getDescription returns the description to set onto TFC, prefixed with the category of the variable
+// as TFC does not support any. An empty one is set when neither of them are defined in order to remove
+// any previously configured one
+func getDescription(v *schemas.VariableWithValue) *string {
+	description := ""
+	if v.Description != nil {
+		description = *v.Description
+	}
+
+	if v.Category != nil && len(*v.Category) > 0 {
+		description = strings.TrimSpace(fmt.Sprintf("[%s] %s", *v.Category, description))
+	}
+
+	return &description
+}
+
+// purgeUnmanagedVariables removes the variables of the workspace which are not defined in the config. When the
+// ownership is restricted to the managed variables, the ones which have not been created by TFCW are left untouched
+func (c *Client) purgeUnmanagedVariables(vars schemas.Variables, e TFCVariables, state *schemas.State, ownership schemas.VariablesOwnership, dryRun bool) error {
+	for _, v := range c.getVariablesToPurge(vars, e, state, ownership) {
+		if !dryRun {
+			log.Warnf("Deleting unmanaged variable %s (%s)", v.Key, v.Category)
+			err := c.TFC.Variables.Delete(c.Context, v.Workspace.ID, v.ID)
+			if err != nil {
+				return fmt.Errorf("error deleting variable %s (%s) on TFC: %s", v.Key, v.Category, err.Error())
+			}
+			state.ForgetVariable(getVariableKind(v.Category), v.Key)
+		} else {
+			log.Warnf("[DRY-RUN] Deleting unmanaged variable %s (%s)", v.Key, v.Category)
+		}
+	}
+
+	return nil
+}
+
+// getVariablesToPurge returns the variables of the workspace which are not defined in the config and
+// that TFCW is allowed to remove
+func (c *Client) getVariablesToPurge(vars schemas.Variables, e TFCVariables, state *schemas.State, ownership schemas.VariablesOwnership) (variables []*tfc.Variable) {
+	definedVariables := map[tfc.CategoryType]map[string]bool{}
 	for _, v := range vars {
-		if _, ok := e[getCategoryType(v.Kind)][v.Name]; ok {
-			delete(e[getCategoryType(v.Kind)], v.Name)
+		if _, ok := definedVariables[getCategoryType(v.Kind)]; !ok {
+			definedVariables[getCategoryType(v.Kind)] = map[string]bool{}
 		}
 
-		// Check if we can have inner values if we are using the Vault provider
-		if p, _ := v.GetProvider(); *p == schemas.VariableProviderVault {
-			if v.Vault.Keys != nil {
-				for _, variableName := range *v.Vault.Keys {
-					if _, ok := e[getCategoryType(v.Kind)][variableName]; ok {
-						delete(e[getCategoryType(v.Kind)], variableName)
-					}
-				}
-			}
+		for _, variableName := range c.getVariableNames(v) {
+			definedVariables[getCategoryType(v.Kind)][variableName] = true
 		}
 	}
 
-	for _, tfeVars := range e {
+	for category, tfeVars := range e {
 		for _, v := range tfeVars {
-			if !dryRun {
-				log.Warnf("Deleting unmanaged variable %s (%s)", v.Key, v.Category)
-				err := c.TFC.Variables.Delete(c.Context, v.Workspace.ID, v.ID)
-				if err != nil {
-					return fmt.Errorf("error deleting variable %s (%s) on TFC: %s", v.Key, v.Category, err.Error())
-				}
-			} else {
-				log.Warnf("[DRY-RUN] Deleting unmanaged variable %s (%s)", v.Key, v.Category)
+			if definedVariables[category][v.Key] {
+				continue
+			}
+
+			if ownership == schemas.VariablesOwnershipManaged && !state.ManagedVariables.Has(getVariableKind(v.Category), v.Key) {
+				log.Debugf("Leaving variable %s (%s) which has not been created by TFCW", v.Key, v.Category)
+				continue
 			}
+
+			variables = append(variables, v)
 		}
 	}
 
-	return nil
+	sort.SliceStable(variables, func(i, j int) bool {
+		if variables[i].Category != variables[j].Category {
+			return variables[i].Category < variables[j].Category
+		}
+		return variables[i].Key < variables[j].Key
+	})
+
+	return
 }
 
-func (c *Client) listVariables(w *tfc.Workspace) (variables TFCVariables, variableExpirations schemas.VariableExpirations, variablesExpirationsTFCVariableID string, err error) {
+func (c *Client) listVariables(w *tfc.Workspace) (variables TFCVariables, internal internalVariables, err error) {
 	variables = make(TFCVariables)
+	internal = make(internalVariables)
 
 	listOptions := tfc.VariableListOptions{
 		ListOptions: tfc.ListOptions{
@@ -141,13 +288,8 @@ func (c *Client) listVariables(w *tfc.Workspace) (variables TFCVariables, variab
 		}
 
 		for _, v := range list.Items {
-			if v.Key == VariableExpirationsName {
-				variablesExpirationsTFCVariableID = v.ID
-				if err = json.Unmarshal([]byte(v.Value), &variableExpirations); err != nil {
-					err = fmt.Errorf("unable to parse the variable ttls currently set on TFC (%s) : %s", VariableExpirationsName, err.Error())
-					// TODO: Remove the existing variable automatically?
-					return
-				}
+			if isInternalVariable(v.Key) {
+				internal[v.Key] = v
 				continue
 			}
 
@@ -178,80 +320,207 @@ func getCategoryType(kind schemas.VariableKind) tfc.CategoryType {
 	return tfc.CategoryType("")
 }
 
+func getVariableKind(category tfc.CategoryType) schemas.VariableKind {
+	switch category {
+	case tfc.CategoryEnv:
+		return schemas.VariableKindEnvironment
+	case tfc.CategoryTerraform:
+		return schemas.VariableKindTerraform
+	}
+
+	return schemas.VariableKind("")
+}
+
 func (c *Client) renderVariablesOnTFC(cfg *schemas.Config, w *tfc.Workspace, dryRun, forceUpdate bool) error {
+	ownership, err := cfg.TFC.GetVariablesOwnership()
+	if err != nil {
+		return err
+	}
+
+	if _, err = cfg.GetSkipUnchangedSensitiveVariables(); err != nil {
+		return err
+	}
+
+	store, state, err := c.loadState(cfg, w)
+	if err != nil {
+		return err
+	}
+
 	// Find existing variables on TFC
-	existingVariables, variableExpirations, variableExpirationsTFCVariableID, err := c.listVariables(w)
+	existingVariables, internal, err := c.listVariables(w)
 	if err != nil {
 		return fmt.Errorf("terraform cloud: %s", err)
 	}
 
-	variablesWithValues := schemas.VariablesWithValues{}
-	values := make(chan *schemas.VariableWithValue)
-	errors := make(chan error)
-	wg := sync.WaitGroup{}
+	if _, ok := store.(*workspaceStateStore); !ok && len(internal) > 0 {
+		log.Warnf("A state is still stored within the environment variables of the workspace, it can be moved to the %s using 'tfcw state migrate --from %s'", store, schemas.StateBackendWorkspace)
+	}
+
+	// Keep a copy of the state as it was loaded in order to only save it if it has changed
+	previousState, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	variableExpirations := state.VariableExpirations
+	managedVariables := state.ManagedVariables
+
+	// Variables which got altered outside of TFCW since they have been rendered are reported
+	for _, drift := range c.getVariablesDrift(cfg, cfg.GetVariables(), existingVariables, state.VariableFingerprints) {
+		log.Warnf("Variable '%s' (%s) has drifted : %s", drift.Name, drift.Kind, drift.Reason)
+	}
 
 	variablesToUpdate := cfg.GetVariables()
 	if !forceUpdate {
-		variablesToUpdate, err = cfg.GetVariablesToUpdate(variableExpirations)
-		if err != nil {
+		if variablesToUpdate, err = cfg.GetVariablesToUpdate(variableExpirations); err != nil {
+			return err
+		}
+
+		if variablesToUpdate, err = c.withReferencedVariables(cfg, variablesToUpdate); err != nil {
 			return err
 		}
 	}
 
-	for _, v := range variablesToUpdate {
+	// All the values are fetched and validated before rendering any of them
+	variablesWithValues, leases, err := c.fetchAndValidateVariablesWithValues(cfg, variablesToUpdate)
+	if err != nil {
+		c.revokeUnusedLeases(variablesToUpdate, leases)
+		return err
+	}
+
+	// Values are written concurrently once they have all been validated
+	errors := make(chan error, len(variablesWithValues))
+	failedValues := map[*schemas.VariableWithValue]bool{}
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, value := range variablesWithValues {
 		wg.Add(1)
-		go func(v *schemas.Variable) {
+		go func(value *schemas.VariableWithValue) {
 			defer wg.Done()
-			fetchedValues, err := c.fetchVariablesWithValues(v)
-			errors <- err
-			for _, value := range fetchedValues {
-				wg.Add(1)
-				values <- value
+			if err := c.renderVariableOnTFC(cfg, w, value, existingVariables, state.VariableFingerprints, dryRun, forceUpdate); err != nil {
+				mutex.Lock()
+				failedValues[value] = true
+				mutex.Unlock()
+				errors <- err
+			}
+		}(value)
+	}
+
+	wg.Wait()
+	close(errors)
+
+	// The leases obtained for the variables which failed to be rendered are revoked, they keep their
+	// previous lease and get expired in order to be updated on the next run. The other ones are
+	// recorded as usual before returning the error
+	renderErr := aggregateErrors(errors)
+	if renderErr != nil {
+		var failedVariables schemas.Variables
+		variablesToUpdate, failedVariables = c.splitFailedVariables(variablesToUpdate, variablesWithValues, failedValues)
+		c.revokeUnusedLeases(failedVariables, leases)
+		for _, v := range failedVariables {
+			if expiration, ok := variableExpirations[v.Kind][v.Name]; ok && !dryRun {
+				expiration.ExpireAt = time.Now()
 			}
-		}(v)
-	}
-
-	go func() {
-		for value := range values {
-			variablesWithValues = append(variablesWithValues, value)
-			errors <- c.renderVariableOnTFC(cfg, w, value, existingVariables, dryRun)
-			wg.Done()
 		}
-	}()
-
-	go func() {
-		wg.Wait()
-		close(values)
-		close(errors)
-	}()
+	}
 
-	for err := range errors {
-		if err != nil {
-			return err
-		}
+	// Values are not rendered when running dry, we do not keep track of their leases.
+	// Otherwise, the previous leases are captured before the expirations get updated and
+	// revoked once the new values have been rendered
+	if dryRun {
+		c.revokeUnusedLeases(variablesToUpdate, leases)
+	} else {
+		defer c.revokeRotatedLeases(variablesToUpdate, variableExpirations.GetLeases(), leases)
 	}
 
 	// Update variable expirations on TFC
-	newVariableExpirations, updateVariableExpirations, err := cfg.ComputeNewVariableExpirations(variablesToUpdate, variableExpirations)
+	newVariableExpirations, updateVariableExpirations, err := cfg.ComputeNewVariableExpirations(variablesToUpdate, variableExpirations, leases)
 	if err != nil {
 		return err
 	}
 
-	if updateVariableExpirations {
-		if err = c.updateVariableExpirations(w, newVariableExpirations, variableExpirationsTFCVariableID); err != nil {
-			return err
+	// Keep track of the variables created by TFCW, the ones defined in the config which were already
+	// present on the workspace are considered as managed as well
+	if !dryRun {
+		for _, v := range variablesWithValues {
+			if !failedValues[v] {
+				managedVariables.Add(v.Kind, v.Name)
+			}
+		}
+
+		for _, v := range cfg.GetVariables() {
+			for _, variableName := range c.getVariableNames(v) {
+				if _, ok := existingVariables[getCategoryType(v.Kind)][variableName]; ok {
+					managedVariables.Add(v.Kind, variableName)
+				}
+			}
 		}
 	}
 
 	if cfg.TFC.PurgeUnmanagedVariables != nil && *cfg.TFC.PurgeUnmanagedVariables {
 		log.Debugf("Looking for unmanaged variables to remove")
-		return c.purgeUnmanagedVariables(cfg.GetVariables(), existingVariables, dryRun)
+		err = c.purgeUnmanagedVariables(cfg.GetVariables(), existingVariables, state, ownership, dryRun)
 	}
 
-	return nil
+	// The leases of the variables which have been removed from the config are not used anymore
+	if _, revokeErr := c.revokeOrphanedLeases(cfg.GetVariables(), newVariableExpirations, dryRun); revokeErr != nil {
+		log.Warnf("unable to revoke the leases of the removed variables : %s", revokeErr)
+	}
+
+	// The state is saved even if the rendering or the purge failed midway through in order to reflect
+	// the values which got written and the deletions
+	state.VariableExpirations = newVariableExpirations
+	updatedState, _ := json.Marshal(state)
+	if !dryRun && (updateVariableExpirations || string(updatedState) != string(previousState)) {
+		if saveErr := store.Save(state); saveErr != nil && err == nil && renderErr == nil {
+			err = fmt.Errorf("unable to save the state into the %s : %s", store, saveErr)
+		}
+	}
+
+	if renderErr != nil {
+		return renderErr
+	}
+	return err
 }
 
-func (c *Client) renderVariablesLocally(vars schemas.Variables) (err error) {
+// splitFailedVariables returns the variables of which all the values have been rendered and the ones
+// which have at least one value that failed to be rendered
+func (c *Client) splitFailedVariables(vars schemas.Variables, values schemas.VariablesWithValues, failedValues map[*schemas.VariableWithValue]bool) (rendered, failed schemas.Variables) {
+	failedNames := map[schemas.VariableKind]map[string]bool{}
+	for _, value := range values {
+		if failedValues[value] {
+			if _, ok := failedNames[value.Kind]; !ok {
+				failedNames[value.Kind] = map[string]bool{}
+			}
+			failedNames[value.Kind][value.Name] = true
+		}
+	}
+
+	for _, v := range vars {
+		hasFailed := false
+		for _, variableName := range c.getVariableNames(v) {
+			if failedNames[v.Kind][variableName] {
+				hasFailed = true
+				break
+			}
+		}
+
+		if hasFailed {
+			failed = append(failed, v)
+		} else {
+			rendered = append(rendered, v)
+		}
+	}
+	return
+}
+
+func (c *Client) renderVariablesLocally(cfg *schemas.Config, vars schemas.Variables) (err error) {
+	// All the values are fetched and validated before writing any of them
+	variablesWithValues, _, err := c.fetchAndValidateVariablesWithValues(cfg, vars)
+	if err != nil {
+		return err
+	}
+
 	envFile, err := os.OpenFile("./tfcw.env", os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0o600)
 	if err != nil {
 		return err
@@ -264,58 +533,131 @@ func (c *Client) renderVariablesLocally(vars schemas.Variables) (err error) {
 	}
 	defer tfFile.Close()
 
-	variablesWithValues := schemas.VariablesWithValues{}
-	values := make(chan *schemas.VariableWithValue)
-	errors := make(chan error)
+	for _, value := range variablesWithValues {
+		if err = c.renderVariableLocally(value, envFile, tfFile); err != nil {
+			return err
+		}
+	}
+
+	return
+}
+
+// fetchAndValidateVariablesWithValues concurrently fetches the values of the variables and
+// checks them against their validation rules, the leases obtained are returned in any case
+func (c *Client) fetchAndValidateVariablesWithValues(cfg *schemas.Config, vars schemas.Variables) (variablesWithValues schemas.VariablesWithValues, leases schemas.VariableLeases, err error) {
+	leases = schemas.VariableLeases{}
+	mutex := sync.Mutex{}
+	errors := make(chan error, len(vars))
 	wg := sync.WaitGroup{}
 
 	for _, v := range vars {
 		wg.Add(1)
 		go func(v *schemas.Variable) {
 			defer wg.Done()
-			fetchedValues, err := c.fetchVariablesWithValues(v)
-			errors <- err
+			fetchedValues, err := c.fetchVariablesWithValues(cfg, v)
+
+			mutex.Lock()
+			defer mutex.Unlock()
 			for _, value := range fetchedValues {
-				wg.Add(1)
-				values <- value
+				if value.Lease != nil {
+					leases.Set(v, value.Lease)
+				}
+				variablesWithValues = append(variablesWithValues, value)
+			}
+
+			if err != nil {
+				errors <- err
 			}
 		}(v)
 	}
 
-	go func() {
-		for value := range values {
-			variablesWithValues = append(variablesWithValues, value)
-			errors <- c.renderVariableLocally(value, envFile, tfFile)
-			wg.Done()
+	wg.Wait()
+	close(errors)
+
+	if err = aggregateErrors(errors); err != nil {
+		return nil, leases, err
+	}
+
+	// All the invalid values are reported at once
+	invalidValues := []string{}
+	for _, value := range variablesWithValues {
+		if err = validations.Validate(value.Value, value.Validations); err != nil {
+			invalidValues = append(invalidValues, fmt.Sprintf("variable '%s' (%s): %s", value.Name, value.Kind, err))
 		}
-	}()
+	}
 
-	go func() {
-		wg.Wait()
-		close(values)
-		close(errors)
-	}()
+	if len(invalidValues) > 0 {
+		sort.Strings(invalidValues)
+		return nil, leases, fmt.Errorf("invalid values : %s", strings.Join(invalidValues, ", "))
+	}
 
+	return variablesWithValues, leases, nil
+}
+
+// aggregateErrors returns the errors sent onto the channel, combined into a single one
+func aggregateErrors(errors <-chan error) error {
+	messages := []string{}
+	var firstErr error
 	for err := range errors {
-		if err != nil {
-			return err
+		if firstErr == nil {
+			firstErr = err
 		}
+		messages = append(messages, err.Error())
 	}
 
-	return
+	if len(messages) < 2 {
+		return firstErr
+	}
+
+	sort.Strings(messages)
+	return fmt.Errorf("%d errors occurred : %s", len(messages), strings.Join(messages, ", "))
 }
 
-func (c *Client) renderVariableOnTFC(cfg *schemas.Config, w *tfc.Workspace, v *schemas.VariableWithValue, e TFCVariables, dryRun bool) (err error) {
+func (c *Client) renderVariableOnTFC(cfg *schemas.Config, w *tfc.Workspace, v *schemas.VariableWithValue, e TFCVariables, fingerprints *schemas.VariableFingerprints, dryRun, forceUpdate bool) (err error) {
+	setVariableDefaults(cfg, v)
+
+	hash, err := getFingerprintHash(cfg, v)
+	if err != nil {
+		return
+	}
+
+	c.fingerprintsMutex.Lock()
+	fingerprint := fingerprints.Get(v.Kind, v.Name)
+	c.fingerprintsMutex.Unlock()
+
+	// Writes are skipped when the value has not changed, unless the update is forced
+	existingVariable := e[getCategoryType(v.Kind)][v.Name]
+	if !forceUpdate && isVariableUnchanged(v, existingVariable, fingerprint, hash) {
+		if !dryRun {
+			c.setFingerprint(fingerprints, v, hash, existingVariable.ID)
+		}
+
+		logUnchangedVariable(v, dryRun)
+		return
+	}
+
 	if !dryRun {
-		if _, err = c.setVariableOnTFC(cfg, w, v, e); err != nil {
+		var tfcVariable *tfc.Variable
+		if tfcVariable, err = c.setVariableOnTFC(w, v, e); err != nil {
 			return
 		}
+
+		c.setFingerprint(fingerprints, v, hash, tfcVariable.ID)
 	}
 
 	logVariableWithValue(v, dryRun)
 	return
 }
 
+func (c *Client) setFingerprint(fingerprints *schemas.VariableFingerprints, v *schemas.VariableWithValue, hash, id string) {
+	c.fingerprintsMutex.Lock()
+	defer c.fingerprintsMutex.Unlock()
+	fingerprints.Set(v.Kind, v.Name, &schemas.VariableFingerprint{
+		Hash: hash,
+		ID:   id,
+	})
+}
+
 func (c *Client) renderVariableLocally(v *schemas.VariableWithValue, envFile, tfFile *os.File) error {
 	switch v.Kind {
 	case schemas.VariableKindEnvironment:
@@ -323,11 +665,15 @@ func (c *Client) renderVariableLocally(v *schemas.VariableWithValue, envFile, tf
 			return err
 		}
 	case schemas.VariableKindTerraform:
+		// Strings are written as HCL literals in order to get their special characters escaped
 		s := ""
-		if v.HCL != nil && *v.HCL {
+		switch {
+		case v.HCL != nil && *v.HCL && v.HasStructuredValue():
+			s = fmt.Sprintf("%s = %s\n", v.Name, hclwrite.TokensForValue(v.TypedValue).Bytes())
+		case v.HCL != nil && *v.HCL:
 			s = fmt.Sprintf("%s = %s\n", v.Name, v.Value)
-		} else {
-			s = fmt.Sprintf("%s = \"%s\"\n", v.Name, v.Value)
+		default:
+			s = fmt.Sprintf("%s = %s\n", v.Name, hclwrite.TokensForValue(cty.StringVal(v.Value)).Bytes())
 		}
 
 		if _, err := tfFile.WriteString(s); err != nil {
@@ -341,83 +687,168 @@ func (c *Client) renderVariableLocally(v *schemas.VariableWithValue, envFile, tf
 	return nil
 }
 
-func (c *Client) fetchVariablesWithValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+func (c *Client) fetchVariablesWithValues(cfg *schemas.Config, v *schemas.Variable) (schemas.VariablesWithValues, error) {
 	if c.isVariableAlreadyProcessed(v.Name, v.Kind) {
 		return nil, fmt.Errorf("duplicate variable '%s' (%s)", v.Name, v.Kind)
 	}
 
-	provider, err := v.GetProvider()
-	if err != nil {
-		return nil, err
-	}
-
-	switch *provider {
-	case schemas.VariableProviderEnv:
-		return schemas.VariablesWithValues{
-			&schemas.VariableWithValue{
-				Variable: *v,
-				Value:    c.Env.GetValue(v.Env),
-			},
-		}, nil
-	case schemas.VariableProviderS5:
-		value, err := c.S5.GetValue(v.S5)
+	return c.getVariableValues(cfg, v)
+}
+
+// variableValuesFetch ensures that the values of a variable are only fetched once
+type variableValuesFetch struct {
+	once   sync.Once
+	values schemas.VariablesWithValues
+	err    error
+}
+
+// getVariableValues fetches the values of a variable using its provider. They are only fetched
+// once so that templates referencing them get the same values as the ones being rendered
+func (c *Client) getVariableValues(cfg *schemas.Config, v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	c.fetchesMutex.Lock()
+	if c.fetches == nil {
+		c.fetches = map[*schemas.Variable]*variableValuesFetch{}
+	}
+
+	f, ok := c.fetches[v]
+	if !ok {
+		f = &variableValuesFetch{}
+		c.fetches[v] = f
+	}
+	c.fetchesMutex.Unlock()
+
+	f.once.Do(func() {
+		provider, err := GetVariableProvider(v)
 		if err != nil {
-			return nil, fmt.Errorf("s5 error: %s", err)
+			f.err = err
+			return
 		}
 
-		return schemas.VariablesWithValues{
-			&schemas.VariableWithValue{
-				Variable: *v,
-				Value:    value,
-			},
-		}, nil
-	case schemas.VariableProviderVault:
-		return c.getVaultValues(v)
-	}
+		p, ok := c.Providers[provider]
+		if !ok {
+			f.err = fmt.Errorf("provider '%s' is not initialized for variable '%s'", provider, v.Name)
+			return
+		}
+
+		// The providers serialize the structured values according to the hcl attribute, its default
+		// is resolved onto a copy of the variable in order to leave the config untouched
+		variable := *v
+		variable.HCL = cfg.GetVariableHCL(v)
+
+		if f.values, f.err = p.GetVariableValues(&variable); f.err != nil {
+			return
+		}
+
+		// The provider is recorded alongside the leases in order to be able to revoke them
+		// once the variable has been removed from the config
+		for _, value := range f.values {
+			if value.Lease != nil {
+				value.Lease.Provider = provider
+			}
+		}
 
-	return nil, fmt.Errorf("unknown provider '%s' for variable '%s'", *provider, v.Name)
+		if len(v.Transforms) == 0 {
+			return
+		}
+
+		// Transforms are applied onto the serialized values, the typed ones do not reflect them anymore.
+		// Neither does the hcl attribute set by the providers for the structured values, the one of the
+		// variable is restored
+		for _, value := range f.values {
+			if value.Value, f.err = transforms.Apply(value.Value, v.Transforms); f.err != nil {
+				f.err = fmt.Errorf("error transforming the value of variable '%s' : %s", value.Name, f.err)
+				return
+			}
+			value.TypedValue = cty.NilVal
+			value.HCL = variable.HCL
+		}
+	})
+
+	return f.values, f.err
 }
 
-// getVaultValues will return an empty value if multiple keys are set
-func (c *Client) getVaultValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
-	values, err := c.Vault.GetValues(v.Vault)
-	if err != nil {
-		return nil, fmt.Errorf("error getting values from vault for variable '%s' : %s", v.Name, err)
+// getVariableValueResolver returns a function looking up the value of any variable
+// defined in the config, given its kind and name
+func (c *Client) getVariableValueResolver(cfg *schemas.Config) func(kind schemas.VariableKind, name string) (string, error) {
+	variables := cfg.GetVariables()
+	return func(kind schemas.VariableKind, name string) (string, error) {
+		for _, v := range variables {
+			if v.Kind != kind {
+				continue
+			}
+
+			for _, variableName := range c.getVariableNames(v) {
+				if variableName != name {
+					continue
+				}
+
+				values, err := c.getVariableValues(cfg, v)
+				if err != nil {
+					return "", err
+				}
+
+				for _, value := range values {
+					if value.Name == name {
+						return value.Value, nil
+					}
+				}
+			}
+		}
+
+		return "", fmt.Errorf("variable '%s' (%s) is not defined", name, kind)
 	}
+}
 
-	// We can map several keys in a single API call
-	if (v.Vault.Key == nil && (v.Vault.Keys == nil || len(*v.Vault.Keys) == 0)) ||
-		(v.Vault.Key != nil && v.Vault.Keys != nil && len(*v.Vault.Keys) > 0) {
-		return nil, fmt.Errorf("you either need to set 'key' or 'keys' when using the Vault provider")
+// withReferencedVariables returns the variables alongside the ones referenced by their templates, recursively.
+// Templates would otherwise fetch values (and obtain leases) of variables which are not going to be rendered
+func (c *Client) withReferencedVariables(cfg *schemas.Config, vars schemas.Variables) (schemas.Variables, error) {
+	variables := append(schemas.Variables{}, vars...)
+	included := map[*schemas.Variable]bool{}
+	for _, v := range variables {
+		included[v] = true
 	}
 
-	if v.Vault.Key != nil {
-		if value, found := values[*v.Vault.Key]; found {
-			return schemas.VariablesWithValues{
-				&schemas.VariableWithValue{
-					Variable: *v,
-					Value:    value,
-				},
-			}, nil
+	for i := 0; i < len(variables); i++ {
+		if variables[i].Template == nil {
+			continue
+		}
+
+		references, err := providerTemplate.GetReferences(variables[i].Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for variable '%s' : %s", variables[i].Name, err)
 		}
-		return nil, fmt.Errorf("key '%s' was not found in secret '%s'", *v.Vault.Key, *v.Vault.Path)
-	}
 
-	variablesWithValues := schemas.VariablesWithValues{}
-	for vaultKey, variableName := range *v.Vault.Keys {
-		if value, found := values[vaultKey]; found {
-			vv := &schemas.VariableWithValue{
-				Variable: *v,
-				Value:    value,
+		for _, r := range references {
+			for _, v := range cfg.GetVariables() {
+				if v.Kind != r.Kind || included[v] {
+					continue
+				}
+
+				for _, variableName := range c.getVariableNames(v) {
+					if variableName == r.Name {
+						log.Debugf("Variable '%s' (%s) is referenced by the template of '%s' (%s), rendering it as well", v.Name, v.Kind, variables[i].Name, variables[i].Kind)
+						variables = append(variables, v)
+						included[v] = true
+						break
+					}
+				}
 			}
-			vv.Name = variableName
-			variablesWithValues = append(variablesWithValues, vv)
-			continue
 		}
-		return nil, fmt.Errorf("key '%s' was not found in secret '%s'", vaultKey, *v.Vault.Path)
 	}
 
-	return variablesWithValues, nil
+	return variables, nil
+}
+
+// getVariableNames returns the name of the variable as well as the ones
+// of the variables its provider can map values onto
+func (c *Client) getVariableNames(v *schemas.Variable) []string {
+	names := []string{v.Name}
+	if provider, err := GetVariableProvider(v); err == nil {
+		if p, ok := c.Providers[provider].(VariableNamesProvider); ok {
+			names = append(names, p.GetVariableNames(v)...)
+		}
+	}
+	return names
 }
 
 func (c *Client) isVariableAlreadyProcessed(name string, kind schemas.VariableKind) bool {
@@ -435,51 +866,27 @@ func (c *Client) isVariableAlreadyProcessed(name string, kind schemas.VariableKi
 
 func logVariableWithValue(v *schemas.VariableWithValue, dryRun bool) {
 	if dryRun {
+		if description := getDescription(v); len(*description) > 0 {
+			log.Infof("[DRY-RUN] Set variable '%s' (%s) : %s - %s", v.Name, v.Kind, secureSensitiveString(v.Value), *description)
+			return
+		}
 		log.Infof("[DRY-RUN] Set variable '%s' (%s) : %s", v.Name, v.Kind, secureSensitiveString(v.Value))
 	} else {
 		log.Infof("Set variable '%s' (%s)", v.Name, v.Kind)
 	}
 }
 
+func logUnchangedVariable(v *schemas.VariableWithValue, dryRun bool) {
+	if dryRun {
+		log.Infof("[DRY-RUN] Variable '%s' (%s) is unchanged, skipping", v.Name, v.Kind)
+	} else {
+		log.Infof("Variable '%s' (%s) is unchanged, skipping", v.Name, v.Kind)
+	}
+}
+
 func secureSensitiveString(sensitive string) string {
 	if len(sensitive) < 4 {
 		return "**********"
 	}
 	return fmt.Sprintf("%s********%s", string(sensitive[0]), string(sensitive[len(sensitive)-1]))
 }
-
-func (c *Client) updateVariableExpirations(w *tfc.Workspace, variableExpirations schemas.VariableExpirations, tfcVariableID string) error {
-	variableExpirationsByte, err := json.Marshal(variableExpirations)
-	if err != nil {
-		return err
-	}
-
-	if len(variableExpirations) == 0 {
-		if tfcVariableID != "" {
-			log.Debug("deleting variable expirations on TFC")
-			return c.TFC.Variables.Delete(c.Context, w.ID, tfcVariableID)
-		}
-		log.Debug("empty variable expirations and already unset on TFC")
-		return nil
-	}
-
-	if tfcVariableID != "" {
-		log.Debug("updating variable expirations on TFC")
-		_, err = c.TFC.Variables.Update(c.Context, w.ID, tfcVariableID, tfc.VariableUpdateOptions{
-			Value: tfc.String(string(variableExpirationsByte)),
-		})
-		return err
-	}
-
-	log.Debug("creating variable expirations on TFC")
-	category := tfc.CategoryEnv
-	_, err = c.TFC.Variables.Create(c.Context, w.ID, tfc.VariableCreateOptions{
-		Key:       tfc.String(VariableExpirationsName),
-		Value:     tfc.String(string(variableExpirationsByte)),
-		Category:  &category,
-		Sensitive: tfc.Bool(false),
-		HCL:       tfc.Bool(false),
-	})
-
-	return err
-}
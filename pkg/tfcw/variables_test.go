@@ -2,11 +2,22 @@ package tfcw
 
 import (
 	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	tfc "github.com/hashicorp/go-tfe"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	providerTemplate "github.com/mvisonneau/tfcw/pkg/providers/template"
+	providerValue "github.com/mvisonneau/tfcw/pkg/providers/value"
 	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
 )
 
 // func TestGetVaultValues(t *testing.T) {
@@ -65,6 +76,56 @@ func TestLogVariableWithValue(t *testing.T) {
 	str.Reset()
 	logVariableWithValue(v, false)
 	assert.Equal(t, "level=info msg=\"Set variable 'foo' (environment)\"\n", str.String())
+
+	// with a description
+	str.Reset()
+	v.Description = pointy.String("foo bar")
+	logVariableWithValue(v, true)
+	assert.Equal(t, "level=info msg=\"[DRY-RUN] Set variable 'foo' (environment) : ********** - foo bar\"\n", str.String())
+
+	// with a category
+	str.Reset()
+	v.Category = pointy.String("database")
+	logVariableWithValue(v, true)
+	assert.Equal(t, "level=info msg=\"[DRY-RUN] Set variable 'foo' (environment) : ********** - [database] foo bar\"\n", str.String())
+}
+
+func TestSetVariableDefaults(t *testing.T) {
+	cfg := &schemas.Config{
+		Defaults: &schemas.Defaults{
+			Variable: &schemas.VariableDefaults{
+				Description: pointy.String("Managed by TFCW"),
+			},
+		},
+	}
+
+	v := &schemas.VariableWithValue{}
+	setVariableDefaults(cfg, v)
+	assert.Equal(t, true, *v.Sensitive)
+	assert.Equal(t, false, *v.HCL)
+	assert.Equal(t, "Managed by TFCW", *v.Description)
+	assert.Equal(t, "Managed by TFCW", *getDescription(v))
+
+	v = &schemas.VariableWithValue{Variable: schemas.Variable{Description: pointy.String("foo")}}
+	setVariableDefaults(cfg, v)
+	assert.Equal(t, "foo", *v.Description)
+
+	// An empty description is set when none is defined
+	v = &schemas.VariableWithValue{}
+	setVariableDefaults(&schemas.Config{}, v)
+	assert.Nil(t, v.Description)
+	assert.Equal(t, "", *getDescription(v))
+
+	// Categories prefix the descriptions
+	cfg.Defaults.Variable.Category = pointy.String("database")
+	v = &schemas.VariableWithValue{}
+	setVariableDefaults(cfg, v)
+	assert.Equal(t, "database", *v.Category)
+	assert.Equal(t, "[database] Managed by TFCW", *getDescription(v))
+
+	v = &schemas.VariableWithValue{Variable: schemas.Variable{Category: pointy.String("network")}}
+	setVariableDefaults(&schemas.Config{}, v)
+	assert.Equal(t, "[network]", *getDescription(v))
 }
 
 func TestSecureSensitiveString(t *testing.T) {
@@ -100,3 +161,420 @@ func TestSecureSensitiveString(t *testing.T) {
 
 // 	return ln, client
 // }
+
+type testCountingProvider struct {
+	count int
+}
+
+func (p *testCountingProvider) GetVariableValues(v *schemas.Variable) (schemas.VariablesWithValues, error) {
+	p.count++
+	return schemas.VariablesWithValues{
+		&schemas.VariableWithValue{
+			Variable: *v,
+			Value:    fmt.Sprintf("%s-%d", v.Literal.Value.AsString(), p.count),
+		},
+	}, nil
+}
+
+func TestFetchVariablesWithValuesTemplate(t *testing.T) {
+	cfg := &schemas.Config{}
+	assert.NoError(t, hclsimple.Decode("tfcw.hcl", []byte(`
+tfvar "user" {
+  value {
+    value = "foo"
+  }
+}
+
+tfvar "dsn" {
+  template {
+    content = "${tfvar.user}@${envvar.HOST}"
+  }
+}
+
+envvar "HOST" {
+  value {
+    value = "bar"
+  }
+}
+
+tfvar "missing" {
+  template {
+    content = "${tfvar.baz}"
+  }
+}
+`), nil, cfg))
+	vars := cfg.GetVariables()
+
+	p := &testCountingProvider{}
+	templateProvider := &providerTemplate.Client{}
+	c := &Client{
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderValue:    p,
+			schemas.VariableProviderTemplate: templateProvider,
+		},
+		ProcessedVariables: map[string]schemas.VariableKind{},
+	}
+	templateProvider.Resolve = c.getVariableValueResolver(cfg)
+
+	values, err := c.fetchVariablesWithValues(cfg, vars[1])
+	assert.NoError(t, err)
+	assert.Equal(t, "foo-1@bar-2", values[0].Value)
+
+	// Values referenced by templates are only fetched once
+	values, err = c.fetchVariablesWithValues(cfg, vars[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "foo-1", values[0].Value)
+	assert.Equal(t, 2, p.count)
+
+	_, err = c.fetchVariablesWithValues(cfg, vars[2])
+	assert.EqualError(t, err, "error rendering the template of variable 'missing' : unable to resolve tfvar.baz : variable 'baz' (terraform) is not defined")
+}
+
+func TestWithReferencedVariables(t *testing.T) {
+	cfg := &schemas.Config{}
+	assert.NoError(t, hclsimple.Decode("tfcw.hcl", []byte(`
+tfvar "user" {
+  value {
+    value = "foo"
+  }
+}
+
+envvar "HOST" {
+  value {
+    value = "bar"
+  }
+}
+
+tfvar "dsn" {
+  template {
+    content = "${tfvar.user}@${envvar.HOST}"
+  }
+}
+
+tfvar "url" {
+  template {
+    content = "https://${tfvar.dsn}"
+  }
+}
+
+tfvar "other" {
+  value {
+    value = "baz"
+  }
+}
+`), nil, cfg))
+	vars := cfg.GetVariables()
+	names := func(variables schemas.Variables) (names []string) {
+		for _, v := range variables {
+			names = append(names, v.Name)
+		}
+		return
+	}
+
+	c := &Client{}
+	variables, err := c.withReferencedVariables(cfg, schemas.Variables{vars[2]})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"url", "dsn", "user", "HOST"}, names(variables))
+
+	// Variables which are already part of the ones to render are not duplicated
+	variables, err = c.withReferencedVariables(cfg, schemas.Variables{vars[0], vars[1]})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user", "dsn", "HOST"}, names(variables))
+}
+
+func TestFetchVariablesWithValuesTransforms(t *testing.T) {
+	cfg := &schemas.Config{}
+	assert.NoError(t, hclsimple.Decode("tfcw.hcl", []byte(`
+tfvar "foo" {
+  value {
+    value = "{\"bar\": \" baz \"}"
+  }
+
+  transform "jsondecode" {
+    key = "bar"
+  }
+
+  transform "trim" {}
+  transform "upper" {}
+}
+
+tfvar "invalid" {
+  value {
+    value = "foo"
+  }
+
+  transform "base64decode" {}
+}
+
+tfvar "structured" {
+  value {
+    value = ["a", "b"]
+  }
+
+  transform "base64encode" {}
+}
+
+tfvar "structured_hcl" {
+  hcl = true
+  value {
+    value = "[\"a\"]"
+  }
+
+  transform "upper" {}
+}
+`), nil, cfg))
+	vars := cfg.GetVariables()
+
+	c := &Client{
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderValue: &providerValue.Client{},
+		},
+		ProcessedVariables: map[string]schemas.VariableKind{},
+	}
+
+	values, err := c.fetchVariablesWithValues(cfg, vars[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "BAZ", values[0].Value)
+	assert.True(t, values[0].TypedValue.IsNull())
+
+	_, err = c.fetchVariablesWithValues(cfg, vars[1])
+	assert.EqualError(t, err, "error transforming the value of variable 'invalid' : unable to apply the 'base64decode' transform : illegal base64 data at input byte 0")
+
+	// Structured values turned into plain strings are not declared as HCL anymore, unless configured so
+	values, err = c.fetchVariablesWithValues(cfg, vars[2])
+	assert.NoError(t, err)
+	assert.Equal(t, "WyJhIiwgImIiXQ==", values[0].Value)
+	assert.Nil(t, values[0].HCL)
+
+	values, err = c.fetchVariablesWithValues(cfg, vars[3])
+	assert.NoError(t, err)
+	assert.Equal(t, `["A"]`, values[0].Value)
+	assert.Equal(t, pointy.Bool(true), values[0].HCL)
+
+	// Transforms are validated prior to fetching any value
+	vars[1].Transforms[0].Type = "foo"
+	assert.EqualError(t, c.ValidateVariables(cfg), "invalid configuration for variable 'invalid': invalid 'foo' transform : unsupported transform type")
+}
+
+func TestFetchVariablesWithValuesHCLDefault(t *testing.T) {
+	cfg := &schemas.Config{}
+	assert.NoError(t, hclsimple.Decode("tfcw.hcl", []byte(`
+defaults {
+  var {
+    hcl = false
+  }
+}
+
+tfvar "structured" {
+  value {
+    value = ["a", "b"]
+  }
+}
+
+tfvar "hcl" {
+  hcl = true
+  value {
+    value = ["a", "b"]
+  }
+}
+`), nil, cfg))
+	vars := cfg.GetVariables()
+
+	c := &Client{
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderValue: &providerValue.Client{},
+		},
+		ProcessedVariables: map[string]schemas.VariableKind{},
+	}
+
+	// The default takes precedence over the declaration of structured values as HCL
+	values, err := c.fetchVariablesWithValues(cfg, vars[0])
+	assert.NoError(t, err)
+	assert.Equal(t, pointy.Bool(false), values[0].HCL)
+
+	values, err = c.fetchVariablesWithValues(cfg, vars[1])
+	assert.NoError(t, err)
+	assert.Equal(t, pointy.Bool(true), values[0].HCL)
+
+	// The config is left untouched
+	assert.Nil(t, vars[0].HCL)
+}
+
+func TestFetchAndValidateVariablesWithValues(t *testing.T) {
+	cfg := &schemas.Config{}
+	assert.NoError(t, hclsimple.Decode("tfcw.hcl", []byte(`
+tfvar "foo" {
+  value {
+    value = "foo"
+  }
+
+  validation {
+    allowed-values = ["foo", "bar"]
+  }
+}
+
+tfvar "bar" {
+  value {
+    value = ""
+  }
+
+  validation {
+    not-empty     = true
+    error-message = "bar must be defined"
+  }
+}
+
+envvar "BAZ" {
+  value {
+    value = "{\"baz\":"
+  }
+
+  validation {
+    json = true
+  }
+}
+`), nil, cfg))
+
+	c := &Client{
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderValue: &providerValue.Client{},
+		},
+		ProcessedVariables: map[string]schemas.VariableKind{},
+	}
+
+	// All the invalid values are reported, nothing gets rendered
+	assert.EqualError(t, c.RenderVariablesLocally(cfg), "invalid values : variable 'BAZ' (environment): must be valid JSON, variable 'bar' (terraform): bar must be defined")
+	_, err := os.Stat("./tfcw.auto.tfvars")
+	assert.True(t, os.IsNotExist(err))
+
+	c.ProcessedVariables = map[string]schemas.VariableKind{}
+	values, _, err := c.fetchAndValidateVariablesWithValues(cfg, cfg.GetVariables()[:1])
+	assert.NoError(t, err)
+	assert.Len(t, values, 1)
+
+	// Misconfigured validations are reported prior to fetching any value
+	cfg.TerraformVariables[0].Validations[0].MinLength = pointy.Int(-1)
+	assert.EqualError(t, c.ValidateVariables(cfg), "invalid configuration for variable 'foo': invalid validation, lengths cannot be negative")
+}
+
+func TestFetchAndValidateVariablesWithValuesErrors(t *testing.T) {
+	cfg := &schemas.Config{}
+	assert.NoError(t, hclsimple.Decode("tfcw.hcl", []byte(`
+tfvar "foo" {
+  template {
+    content = "${tfvar.bar}"
+  }
+}
+
+envvar "BAZ" {
+  template {
+    content = "${envvar.QUX}"
+  }
+}
+`), nil, cfg))
+
+	templateProvider := &providerTemplate.Client{}
+	c := &Client{
+		Providers: map[schemas.VariableProvider]Provider{
+			schemas.VariableProviderTemplate: templateProvider,
+		},
+		ProcessedVariables: map[string]schemas.VariableKind{},
+	}
+	templateProvider.Resolve = c.getVariableValueResolver(cfg)
+
+	// All the errors are reported
+	_, _, err := c.fetchAndValidateVariablesWithValues(cfg, cfg.GetVariables())
+	assert.EqualError(t, err, "2 errors occurred : "+
+		"error rendering the template of variable 'BAZ' : unable to resolve envvar.QUX : variable 'QUX' (environment) is not defined, "+
+		"error rendering the template of variable 'foo' : unable to resolve tfvar.bar : variable 'bar' (terraform) is not defined")
+}
+
+func TestAggregateErrors(t *testing.T) {
+	errors := make(chan error, 2)
+	close(errors)
+	assert.NoError(t, aggregateErrors(errors))
+
+	errors = make(chan error, 2)
+	errors <- fmt.Errorf("foo")
+	close(errors)
+	assert.EqualError(t, aggregateErrors(errors), "foo")
+}
+
+func TestRenderVariableLocally(t *testing.T) {
+	dir := t.TempDir()
+	envFile, err := os.Create(filepath.Join(dir, "tfcw.env"))
+	assert.NoError(t, err)
+	defer envFile.Close()
+
+	tfFile, err := os.Create(filepath.Join(dir, "tfcw.auto.tfvars"))
+	assert.NoError(t, err)
+	defer tfFile.Close()
+
+	c := &Client{}
+	values := schemas.VariablesWithValues{
+		{Variable: schemas.Variable{Name: "foo", Kind: schemas.VariableKindTerraform}, Value: "b\"a\nr ${baz}"},
+		{Variable: schemas.Variable{Name: "bar", Kind: schemas.VariableKindTerraform, HCL: pointy.Bool(true)}, Value: `["a", "b"]`},
+		{Variable: schemas.Variable{Name: "baz", Kind: schemas.VariableKindTerraform, HCL: pointy.Bool(true)}, Value: `{"a":"b"}`, TypedValue: cty.MapVal(map[string]cty.Value{"a": cty.StringVal("b")})},
+	}
+
+	for _, v := range values {
+		assert.NoError(t, c.renderVariableLocally(v, envFile, tfFile))
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "tfcw.auto.tfvars"))
+	assert.NoError(t, err)
+	assert.Equal(t, "foo = \"b\\\"a\\nr $${baz}\"\nbar = [\"a\", \"b\"]\nbaz = {\n  a = \"b\"\n}\n", string(content))
+}
+
+func TestInternalVariables(t *testing.T) {
+	i := internalVariables{
+		VariableExpirationsName: &tfc.Variable{ID: "var-1", Value: `{"terraform":{"foo":{"ttl":60000000000,"expire_at":"2022-01-01T00:00:00Z"}}}`},
+		ManagedVariablesName:    &tfc.Variable{ID: "var-2", Value: `{"terraform":["foo"],"environment":["BAR"]}`},
+	}
+
+	variableExpirations, err := i.getVariableExpirations()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, variableExpirations[schemas.VariableKindTerraform]["foo"].TTL)
+
+	managedVariables, err := i.getManagedVariables()
+	assert.NoError(t, err)
+	assert.True(t, managedVariables.Has(schemas.VariableKindEnvironment, "BAR"))
+
+	// Workspaces on which TFCW has not registered any variable yet
+	managedVariables, err = internalVariables{}.getManagedVariables()
+	assert.NoError(t, err)
+	assert.Equal(t, schemas.ManagedVariables{}, managedVariables)
+
+	i[ManagedVariablesName].Value = "foo"
+	_, err = i.getManagedVariables()
+	assert.EqualError(t, err, "unable to parse the managed variables currently set on TFC (__TFCW_MANAGED_VARIABLES) : invalid character 'o' in literal false (expecting 'a')")
+}
+
+func TestPurgeUnmanagedVariablesOwnership(t *testing.T) {
+	var str bytes.Buffer
+	log.SetOutput(&str)
+	log.SetFormatter(&log.TextFormatter{DisableTimestamp: true})
+
+	c := &Client{}
+	e := func() TFCVariables {
+		return TFCVariables{
+			tfc.CategoryTerraform: {
+				"foo": &tfc.Variable{Key: "foo", Category: tfc.CategoryTerraform},
+				"bar": &tfc.Variable{Key: "bar", Category: tfc.CategoryTerraform},
+			},
+			tfc.CategoryEnv: {
+				"BAZ": &tfc.Variable{Key: "BAZ", Category: tfc.CategoryEnv},
+			},
+		}
+	}
+	vars := schemas.Variables{{Name: "foo", Kind: schemas.VariableKindTerraform}}
+	state := &schemas.State{ManagedVariables: schemas.ManagedVariables{schemas.VariableKindEnvironment: {"BAZ"}}}
+
+	assert.NoError(t, c.purgeUnmanagedVariables(vars, e(), state, schemas.VariablesOwnershipManaged, true))
+	assert.Equal(t, "level=warning msg=\"[DRY-RUN] Deleting unmanaged variable BAZ (env)\"\n", str.String())
+
+	str.Reset()
+	assert.NoError(t, c.purgeUnmanagedVariables(vars, e(), state, schemas.VariablesOwnershipAll, true))
+	assert.Contains(t, str.String(), "[DRY-RUN] Deleting unmanaged variable bar (terraform)")
+	assert.Contains(t, str.String(), "[DRY-RUN] Deleting unmanaged variable BAZ (env)")
+}
@@ -190,39 +190,81 @@ func (c *Client) SetWorkspaceOperations(w *tfc.Workspace, operations bool) (err
 	return
 }
 
-// DeleteAllWorkspaceVariables delete the all the variables present on the workspace
-func (c *Client) DeleteAllWorkspaceVariables(w *tfc.Workspace) (err error) {
-	existingVariables, _, _, err := c.listVariables(w)
+// DeleteAllWorkspaceVariables delete the all the variables present on the workspace, restricted to
+// the ones created by TFCW when the variables ownership is set to schemas.VariablesOwnershipManaged
+func (c *Client) DeleteAllWorkspaceVariables(cfg *schemas.Config, w *tfc.Workspace) (err error) {
+	ownership, err := cfg.TFC.GetVariablesOwnership()
+	if err != nil {
+		return
+	}
+
+	existingVariables, _, err := c.listVariables(w)
+	if err != nil {
+		return
+	}
 
+	store, state, err := c.loadState(cfg, w)
+	if err != nil {
+		return
+	}
+
+	// The state is saved even if a deletion fails in order to reflect the previous ones
+deletion:
 	for _, vars := range existingVariables {
 		for _, v := range vars {
+			kind := getVariableKind(v.Category)
+			if ownership == schemas.VariablesOwnershipManaged && !state.ManagedVariables.Has(kind, v.Key) {
+				log.Infof("leaving variable %s which has not been created by TFCW", v.Key)
+				continue
+			}
+
 			if err = c.TFC.Variables.Delete(c.Context, w.ID, v.ID); err != nil {
-				return err
+				err = fmt.Errorf("error deleting variable %s (%s) on TFC: %s", v.Key, v.Category, err.Error())
+				break deletion
 			}
+			state.ForgetVariable(kind, v.Key)
 			log.Infof("deleted variable %s", v.Key)
 		}
 	}
 
+	if saveErr := store.Save(state); saveErr != nil && err == nil {
+		err = saveErr
+	}
+
 	return
 }
 
-// DeleteWorkspaceVariables delete the variables list passed as an argument if they are present
+// DeleteWorkspaceVariables delete the variables defined in the config if they are present
 // on the workspace
-func (c *Client) DeleteWorkspaceVariables(w *tfc.Workspace, variables schemas.Variables) (err error) {
-	existingVariables, _, _, err := c.listVariables(w)
+func (c *Client) DeleteWorkspaceVariables(cfg *schemas.Config, w *tfc.Workspace) (err error) {
+	existingVariables, _, err := c.listVariables(w)
+	if err != nil {
+		return
+	}
 
-	for _, v := range variables {
+	store, state, err := c.loadState(cfg, w)
+	if err != nil {
+		return
+	}
+
+	for _, v := range cfg.GetVariables() {
 		kind := getCategoryType(v.Kind)
 		if _, ok := existingVariables[kind]; ok {
 			if _, ok := existingVariables[kind][v.Name]; ok {
 				if err = c.TFC.Variables.Delete(c.Context, w.ID, existingVariables[kind][v.Name].ID); err != nil {
-					return err
+					err = fmt.Errorf("error deleting variable %s (%s) on TFC: %s", v.Name, kind, err.Error())
+					break
 				}
+				state.ForgetVariable(v.Kind, v.Name)
 				log.Infof("deleted variable %s", v.Name)
 			}
 		}
 	}
 
+	if saveErr := store.Save(state); saveErr != nil && err == nil {
+		err = saveErr
+	}
+
 	return
 }
 
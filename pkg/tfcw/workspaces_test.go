@@ -1,3 +1,95 @@
 package tfcw
 
-// TODO: Implement!
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	tfc "github.com/hashicorp/go-tfe"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/stretchr/testify/assert"
+)
+
+const testWorkspaceVariables = `{"data":[
+{"id":"var-foo","type":"vars","attributes":{"key":"foo","value":"foo","category":"terraform"}},
+{"id":"var-bar","type":"vars","attributes":{"key":"BAR","value":"bar","category":"env"}}
+],"meta":{"pagination":{"current-page":1,"total-pages":1}}}`
+
+// newTestTFCClient returns a client targeting a fake TFC API listing the variables of the
+// workspace 'ws-test', the deletion of the variables whose ID is part of failingDeletions fails
+func newTestTFCClient(t *testing.T, failingDeletions ...string) (*Client, *[]string) {
+	deleted := []string{}
+	mutex := sync.Mutex{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v2/ping":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v2/workspaces/ws-test/vars":
+			w.Header().Set("Content-Type", "application/vnd.api+json")
+			_, _ = w.Write([]byte(testWorkspaceVariables))
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v2/workspaces/ws-test/vars/"):
+			id := strings.TrimPrefix(r.URL.Path, "/api/v2/workspaces/ws-test/vars/")
+			for _, failingID := range failingDeletions {
+				if id == failingID {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+			}
+
+			mutex.Lock()
+			deleted = append(deleted, id)
+			mutex.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	tfcClient, err := tfc.NewClient(&tfc.Config{Address: server.URL, Token: "_"})
+	assert.NoError(t, err)
+
+	return &Client{
+		TFC:     tfcClient,
+		Context: context.Background(),
+	}, &deleted
+}
+
+func newTestFileStateConfig(t *testing.T) *schemas.Config {
+	backend := schemas.StateBackendFile
+	path := filepath.Join(t.TempDir(), "state.json")
+	return &schemas.Config{
+		State: &schemas.StateStorage{
+			Backend: &backend,
+			File:    &schemas.StateFile{Path: &path},
+		},
+	}
+}
+
+func TestDeleteAllWorkspaceVariables(t *testing.T) {
+	c, deleted := newTestTFCClient(t)
+	assert.NoError(t, c.DeleteAllWorkspaceVariables(newTestFileStateConfig(t), &tfc.Workspace{ID: "ws-test"}))
+	assert.ElementsMatch(t, []string{"var-foo", "var-bar"}, *deleted)
+
+	// Failures are reported, whichever category gets processed first
+	c, _ = newTestTFCClient(t, "var-foo")
+	assert.EqualError(t, c.DeleteAllWorkspaceVariables(newTestFileStateConfig(t), &tfc.Workspace{ID: "ws-test"}), "error deleting variable foo (terraform) on TFC: resource not found")
+}
+
+func TestDeleteWorkspaceVariables(t *testing.T) {
+	cfg := newTestFileStateConfig(t)
+	cfg.TerraformVariables = schemas.Variables{{Name: "foo"}}
+	cfg.EnvironmentVariables = schemas.Variables{{Name: "BAR"}}
+
+	c, deleted := newTestTFCClient(t)
+	assert.NoError(t, c.DeleteWorkspaceVariables(cfg, &tfc.Workspace{ID: "ws-test"}))
+	assert.ElementsMatch(t, []string{"var-foo", "var-bar"}, *deleted)
+
+	c, _ = newTestTFCClient(t, "var-bar")
+	assert.EqualError(t, c.DeleteWorkspaceVariables(cfg, &tfc.Workspace{ID: "ws-test"}), "error deleting variable BAR (env) on TFC: resource not found")
+}
@@ -0,0 +1,108 @@
+package transforms
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mvisonneau/tfcw/pkg/jsonpath"
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+// Validate ensures that the transforms are supported and have the attributes they require
+func Validate(transforms []*schemas.Transform) error {
+	for _, t := range transforms {
+		if err := validate(t); err != nil {
+			return fmt.Errorf("invalid '%s' transform : %s", t.Type, err)
+		}
+	}
+	return nil
+}
+
+func validate(t *schemas.Transform) error {
+	switch schemas.TransformType(t.Type) {
+	case schemas.TransformTypeBase64Encode,
+		schemas.TransformTypeBase64Decode,
+		schemas.TransformTypeTrim,
+		schemas.TransformTypeJSONEncode,
+		schemas.TransformTypeLower,
+		schemas.TransformTypeUpper,
+		schemas.TransformTypeSHA256:
+	case schemas.TransformTypeJSONDecode:
+		if t.Key == nil {
+			return fmt.Errorf("'key' is required")
+		}
+	case schemas.TransformTypeReplace:
+		if t.Pattern == nil {
+			return fmt.Errorf("'pattern' is required")
+		}
+
+		if _, err := regexp.Compile(*t.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern : %s", err)
+		}
+	default:
+		return fmt.Errorf("unsupported transform type")
+	}
+
+	if t.Key != nil && schemas.TransformType(t.Type) != schemas.TransformTypeJSONDecode {
+		return fmt.Errorf("'key' can only be used with the '%s' transform", schemas.TransformTypeJSONDecode)
+	}
+
+	if (t.Pattern != nil || t.Replacement != nil) && schemas.TransformType(t.Type) != schemas.TransformTypeReplace {
+		return fmt.Errorf("'pattern' and 'replacement' can only be used with the '%s' transform", schemas.TransformTypeReplace)
+	}
+
+	return nil
+}
+
+// Apply returns the value once transformed, transforms are applied in the order they are defined
+func Apply(value string, transforms []*schemas.Transform) (string, error) {
+	if err := Validate(transforms); err != nil {
+		return "", err
+	}
+
+	for _, t := range transforms {
+		var err error
+		if value, err = apply(value, t); err != nil {
+			return "", fmt.Errorf("unable to apply the '%s' transform : %s", t.Type, err)
+		}
+	}
+
+	return value, nil
+}
+
+func apply(value string, t *schemas.Transform) (string, error) {
+	switch schemas.TransformType(t.Type) {
+	case schemas.TransformTypeBase64Encode:
+		return base64.StdEncoding.EncodeToString([]byte(value)), nil
+	case schemas.TransformTypeBase64Decode:
+		b, err := base64.StdEncoding.DecodeString(value)
+		return string(b), err
+	case schemas.TransformTypeTrim:
+		return strings.TrimSpace(value), nil
+	case schemas.TransformTypeJSONEncode:
+		b, err := json.Marshal(value)
+		return string(b), err
+	case schemas.TransformTypeJSONDecode:
+		return jsonpath.Extract([]byte(value), *t.Key)
+	case schemas.TransformTypeLower:
+		return strings.ToLower(value), nil
+	case schemas.TransformTypeUpper:
+		return strings.ToUpper(value), nil
+	case schemas.TransformTypeSHA256:
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:]), nil
+	case schemas.TransformTypeReplace:
+		replacement := ""
+		if t.Replacement != nil {
+			replacement = *t.Replacement
+		}
+		return regexp.MustCompile(*t.Pattern).ReplaceAllString(value, replacement), nil
+	}
+
+	return "", fmt.Errorf("unsupported transform type")
+}
@@ -0,0 +1,68 @@
+package transforms
+
+import (
+	"testing"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	for _, tc := range []struct {
+		value      string
+		transforms []*schemas.Transform
+		expected   string
+	}{
+		{"foo", []*schemas.Transform{{Type: string(schemas.TransformTypeBase64Encode)}}, "Zm9v"},
+		{"Zm9v", []*schemas.Transform{{Type: string(schemas.TransformTypeBase64Decode)}}, "foo"},
+		{" foo\n", []*schemas.Transform{{Type: string(schemas.TransformTypeTrim)}}, "foo"},
+		{`fo"o`, []*schemas.Transform{{Type: string(schemas.TransformTypeJSONEncode)}}, `"fo\"o"`},
+		{`{"foo":{"bar":["baz"]}}`, []*schemas.Transform{{Type: string(schemas.TransformTypeJSONDecode), Key: pointy.String("foo.bar.0")}}, "baz"},
+		{"FoO", []*schemas.Transform{{Type: string(schemas.TransformTypeLower)}}, "foo"},
+		{"FoO", []*schemas.Transform{{Type: string(schemas.TransformTypeUpper)}}, "FOO"},
+		{"foo", []*schemas.Transform{{Type: string(schemas.TransformTypeSHA256)}}, "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"},
+		{"foo-bar-baz", []*schemas.Transform{{Type: string(schemas.TransformTypeReplace), Pattern: pointy.String("-(ba)"), Replacement: pointy.String("_${1}")}}, "foo_bar_baz"},
+		{"foo-bar", []*schemas.Transform{{Type: string(schemas.TransformTypeReplace), Pattern: pointy.String("-")}}, "foobar"},
+		// Transforms are applied in order
+		{
+			`{"kubeconfig":"  apiVersion: v1\n"}`,
+			[]*schemas.Transform{
+				{Type: string(schemas.TransformTypeJSONDecode), Key: pointy.String("kubeconfig")},
+				{Type: string(schemas.TransformTypeTrim)},
+				{Type: string(schemas.TransformTypeBase64Encode)},
+			},
+			"YXBpVmVyc2lvbjogdjE=",
+		},
+		{"foo", nil, "foo"},
+	} {
+		value, err := Apply(tc.value, tc.transforms)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, value)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	_, err := Apply("foo", []*schemas.Transform{{Type: string(schemas.TransformTypeBase64Decode)}})
+	assert.EqualError(t, err, "unable to apply the 'base64decode' transform : illegal base64 data at input byte 0")
+
+	_, err = Apply("foo", []*schemas.Transform{{Type: string(schemas.TransformTypeJSONDecode), Key: pointy.String("foo")}})
+	assert.Error(t, err)
+
+	_, err = Apply("foo", []*schemas.Transform{{Type: "foo"}})
+	assert.EqualError(t, err, "invalid 'foo' transform : unsupported transform type")
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate([]*schemas.Transform{
+		{Type: string(schemas.TransformTypeTrim)},
+		{Type: string(schemas.TransformTypeJSONDecode), Key: pointy.String("foo")},
+		{Type: string(schemas.TransformTypeReplace), Pattern: pointy.String("foo")},
+	}))
+
+	assert.EqualError(t, Validate([]*schemas.Transform{{Type: string(schemas.TransformTypeJSONDecode)}}), "invalid 'jsondecode' transform : 'key' is required")
+	assert.EqualError(t, Validate([]*schemas.Transform{{Type: string(schemas.TransformTypeReplace)}}), "invalid 'replace' transform : 'pattern' is required")
+	assert.EqualError(t, Validate([]*schemas.Transform{{Type: string(schemas.TransformTypeReplace), Pattern: pointy.String("(")}}), "invalid 'replace' transform : invalid pattern : error parsing regexp: missing closing ): `(`")
+	assert.EqualError(t, Validate([]*schemas.Transform{{Type: string(schemas.TransformTypeTrim), Key: pointy.String("foo")}}), "invalid 'trim' transform : 'key' can only be used with the 'jsondecode' transform")
+	assert.EqualError(t, Validate([]*schemas.Transform{{Type: string(schemas.TransformTypeLower), Pattern: pointy.String("foo")}}), "invalid 'lower' transform : 'pattern' and 'replacement' can only be used with the 'replace' transform")
+}
@@ -0,0 +1,87 @@
+package validations
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+)
+
+// Check ensures that the validation rules are correctly configured
+func Check(validations []*schemas.Validation) error {
+	for _, v := range validations {
+		if v.Regex != nil {
+			if _, err := regexp.Compile(*v.Regex); err != nil {
+				return fmt.Errorf("invalid validation regex : %s", err)
+			}
+		}
+
+		if (v.MinLength != nil && *v.MinLength < 0) || (v.MaxLength != nil && *v.MaxLength < 0) {
+			return fmt.Errorf("invalid validation, lengths cannot be negative")
+		}
+
+		if v.MinLength != nil && v.MaxLength != nil && *v.MinLength > *v.MaxLength {
+			return fmt.Errorf("invalid validation, min-length (%d) is greater than max-length (%d)", *v.MinLength, *v.MaxLength)
+		}
+	}
+	return nil
+}
+
+// Validate returns an error describing the first rule the value does not comply with,
+// the value itself is never part of the error in order to avoid leaking it
+func Validate(value string, validations []*schemas.Validation) error {
+	if err := Check(validations); err != nil {
+		return err
+	}
+
+	for _, v := range validations {
+		if err := validate(value, v); err != nil {
+			if v.ErrorMessage != nil {
+				return fmt.Errorf("%s", *v.ErrorMessage)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func validate(value string, v *schemas.Validation) error {
+	if v.NotEmpty != nil && *v.NotEmpty && len(value) == 0 {
+		return fmt.Errorf("must not be empty")
+	}
+
+	if v.MinLength != nil && utf8.RuneCountInString(value) < *v.MinLength {
+		return fmt.Errorf("must be at least %d characters long", *v.MinLength)
+	}
+
+	if v.MaxLength != nil && utf8.RuneCountInString(value) > *v.MaxLength {
+		return fmt.Errorf("must be at most %d characters long", *v.MaxLength)
+	}
+
+	if v.Regex != nil && !regexp.MustCompile(*v.Regex).MatchString(value) {
+		return fmt.Errorf("must match the regular expression '%s'", *v.Regex)
+	}
+
+	if v.AllowedValues != nil {
+		allowed := false
+		for _, allowedValue := range *v.AllowedValues {
+			if value == allowedValue {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return fmt.Errorf("must be one of : %s", strings.Join(*v.AllowedValues, ", "))
+		}
+	}
+
+	if v.JSON != nil && *v.JSON && !json.Valid([]byte(value)) {
+		return fmt.Errorf("must be valid JSON")
+	}
+
+	return nil
+}
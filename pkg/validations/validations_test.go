@@ -0,0 +1,54 @@
+package validations
+
+import (
+	"testing"
+
+	"github.com/mvisonneau/tfcw/pkg/schemas"
+	"github.com/openlyinc/pointy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		value       string
+		validation  *schemas.Validation
+		expectedErr string
+	}{
+		{"foo", &schemas.Validation{NotEmpty: pointy.Bool(true)}, ""},
+		{"", &schemas.Validation{NotEmpty: pointy.Bool(true)}, "must not be empty"},
+		{"", &schemas.Validation{NotEmpty: pointy.Bool(false)}, ""},
+		{"foo", &schemas.Validation{MinLength: pointy.Int(3)}, ""},
+		{"fo", &schemas.Validation{MinLength: pointy.Int(3)}, "must be at least 3 characters long"},
+		{"föo", &schemas.Validation{MaxLength: pointy.Int(3)}, ""},
+		{"fooo", &schemas.Validation{MaxLength: pointy.Int(3)}, "must be at most 3 characters long"},
+		{"AKIAFOO", &schemas.Validation{Regex: pointy.String("^AKIA[A-Z]+$")}, ""},
+		{"foo", &schemas.Validation{Regex: pointy.String("^AKIA[A-Z]+$")}, "must match the regular expression '^AKIA[A-Z]+$'"},
+		{"bar", &schemas.Validation{AllowedValues: &[]string{"foo", "bar"}}, ""},
+		{"baz", &schemas.Validation{AllowedValues: &[]string{"foo", "bar"}}, "must be one of : foo, bar"},
+		{`{"foo":"bar"}`, &schemas.Validation{JSON: pointy.Bool(true)}, ""},
+		{`{"foo":`, &schemas.Validation{JSON: pointy.Bool(true)}, "must be valid JSON"},
+		{"", &schemas.Validation{NotEmpty: pointy.Bool(true), ErrorMessage: pointy.String("the AWS key is missing")}, "the AWS key is missing"},
+	} {
+		err := Validate(tc.value, []*schemas.Validation{tc.validation})
+		if tc.expectedErr == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tc.expectedErr)
+		}
+	}
+
+	// All the validations are checked
+	assert.EqualError(t, Validate("foo", []*schemas.Validation{
+		{NotEmpty: pointy.Bool(true)},
+		{MinLength: pointy.Int(4), ErrorMessage: pointy.String("too short")},
+	}), "too short")
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, Check([]*schemas.Validation{{MinLength: pointy.Int(1), MaxLength: pointy.Int(1)}}))
+	assert.EqualError(t, Check([]*schemas.Validation{{Regex: pointy.String("(")}}), "invalid validation regex : error parsing regexp: missing closing ): `(`")
+	assert.EqualError(t, Check([]*schemas.Validation{{MinLength: pointy.Int(-1)}}), "invalid validation, lengths cannot be negative")
+	assert.EqualError(t, Check([]*schemas.Validation{{MinLength: pointy.Int(2), MaxLength: pointy.Int(1)}}), "invalid validation, min-length (2) is greater than max-length (1)")
+
+	assert.Error(t, Validate("foo", []*schemas.Validation{{Regex: pointy.String("(")}}))
+}